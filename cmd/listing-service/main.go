@@ -0,0 +1,99 @@
+package main
+
+import (
+	"99-backend-exercise/internal/listing"
+	"99-backend-exercise/pkg/database"
+	"99-backend-exercise/pkg/idempotency"
+	"99-backend-exercise/pkg/logging"
+	"99-backend-exercise/pkg/metrics"
+	"99-backend-exercise/pkg/tracing"
+	"context"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+func main() {
+	envErr := godotenv.Load()
+	logging.Setup(logging.NewConfig("listing-service"))
+	logger := logging.Logger("server")
+	if envErr != nil {
+		logger.Warn("No .env file found")
+	}
+	tracerProvider, err := tracing.Setup(context.Background(), tracing.NewConfig("listing-service"))
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", "error", err)
+	}
+	defer func() {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
+	dbConfig := database.NewDatabaseConfig()
+	dbConn, err := database.Connect(dbConfig)
+	if err != nil {
+		logging.Fatal(logger, "Failed to connect to database", "error", err)
+	}
+	defer dbConn.Close()
+	migrator, err := database.NewMigrator(dbConn, "listing-service", listing.Migrations())
+	if err != nil {
+		logging.Fatal(logger, "Failed to load migrations", "error", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.RunMigrateCommand(migrator, os.Args[2:]); err != nil {
+			logging.Fatal(logger, "Migration failed", "error", err)
+		}
+		return
+	}
+	if dbConfig.MigrateOnStart {
+		if _, err := migrator.Up(); err != nil {
+			logging.Fatal(logger, "Failed to migrate database", "error", err)
+		}
+	}
+	listingConfig := listing.NewConfig()
+	var ownerVerifier listing.OwnerVerifier
+	if listingConfig.VerifyOwner {
+		ownerVerifier = listing.NewOwnerVerifier(listingConfig.UserServiceURL)
+	}
+	listingRepo := listing.NewRepository(dbConn.DB)
+	listingService := listing.NewService(listingRepo, ownerVerifier)
+	listingHandler := listing.NewHandler(listingService)
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(otelgin.Middleware("listing-service"))
+	router.Use(logging.AccessLogMiddleware(logging.Logger("http")))
+	router.Use(logging.RecoveryMiddleware(logger))
+	router.Use(metrics.Middleware("listing-service"))
+	router.GET("/metrics", metrics.Handler())
+	v1 := router.Group("/")
+	v1.Use(idempotency.NewStore(dbConn.DB, "listing_idempotency_keys", idempotency.NewConfig()).Middleware())
+	{
+		v1.GET("/listings/ping", listingHandler.Ping)
+		v1.GET("/listings", listingHandler.GetListings)
+		v1.POST("/listings", listingHandler.CreateListing)
+		v1.GET("/listings/:id", listingHandler.GetListingByID)
+		v1.PATCH("/listings/:id", listingHandler.UpdateListing)
+		v1.DELETE("/listings/:id", listingHandler.DeleteListing)
+		v1.POST("/listings/:id/transition", listingHandler.TransitionListing)
+		v1.GET("/listings/:id/transitions", listingHandler.GetStatusTransitions)
+		v1.GET("/listings/:id/price-history", listingHandler.GetPriceHistory)
+	}
+	router.GET("/health", func(c *gin.Context) {
+		dbHealth := dbConn.Health()
+		status, code := "ok", 200
+		if dbHealth.Status != "ok" {
+			status, code = "degraded", 503
+		}
+		c.JSON(code, gin.H{"status": status, "service": "listing-service", "database": dbHealth})
+	})
+	port := os.Getenv("LISTING_SERVICE_PORT")
+	if port == "" {
+		port = "6000"
+	}
+	logger.Info("Listing service starting", "port", port)
+	if err := router.Run(":" + port); err != nil {
+		logging.Fatal(logger, "Failed to start server", "error", err)
+	}
+}
@@ -2,46 +2,84 @@ package main
 
 import (
 	"99-backend-exercise/internal/publicapi"
-	"log"
+	"99-backend-exercise/pkg/logging"
+	"99-backend-exercise/pkg/metrics"
+	"99-backend-exercise/pkg/tracing"
+	"context"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found")
+	envErr := godotenv.Load()
+	logging.Setup(logging.NewConfig("public-api"))
+	logger := logging.Logger("server")
+	if envErr != nil {
+		logger.Warn("No .env file found")
 	}
-	userServiceURL := os.Getenv("USER_SERVICE_URL")
-	if userServiceURL == "" {
-		userServiceURL = "http://localhost:8001"
+	tracerProvider, err := tracing.Setup(context.Background(), tracing.NewConfig("public-api"))
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", "error", err)
 	}
-	listingServiceURL := os.Getenv("LISTING_SERVICE_URL")
-	if listingServiceURL == "" {
-		listingServiceURL = "http://localhost:6000"
+	defer func() {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
+	config := publicapi.NewConfig()
+	if err := config.Validate(); err != nil {
+		logging.Fatal(logger, "Invalid configuration", "error", err)
 	}
-	serviceClient := publicapi.NewServiceClient(userServiceURL, listingServiceURL)
-	publicAPIService := publicapi.NewService(serviceClient)
+	serviceClient := publicapi.NewServiceClient(config)
+	userCache := publicapi.NewUserCache(config.UserCache)
+	publicAPIService := publicapi.NewService(serviceClient, userCache, config)
+	publicapi.RegisterMetrics(serviceClient, userCache, publicAPIService)
 	publicAPIHandler := publicapi.NewHandler(publicAPIService)
-	router := gin.Default()
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(otelgin.Middleware("public-api"))
+	router.Use(logging.AccessLogMiddleware(logging.Logger("http")))
+	router.Use(logging.RecoveryMiddleware(logger))
+	router.Use(metrics.Middleware("public-api"))
+	router.GET("/metrics", metrics.Handler())
 	publicAPIGroup := router.Group("/public-api")
 	{
 		publicAPIGroup.GET("/listings", publicAPIHandler.GetListings)
 		publicAPIGroup.POST("/users", publicAPIHandler.CreateUser)
+		publicAPIGroup.PATCH("/users/:id", publicAPIHandler.UpdateUser)
+		publicAPIGroup.DELETE("/users/:id", publicAPIHandler.DeleteUser)
 		publicAPIGroup.POST("/listings", publicAPIHandler.CreateListing)
+		publicAPIGroup.GET("/listings/:id", publicAPIHandler.GetListing)
+		publicAPIGroup.PATCH("/listings/:id", publicAPIHandler.UpdateListing)
+		publicAPIGroup.DELETE("/listings/:id", publicAPIHandler.DeleteListing)
 	}
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "public-api"})
+		status := "ok"
+		breakers := serviceClient.CircuitBreakers()
+		for _, breaker := range breakers {
+			if breaker.State != publicapi.CircuitClosed {
+				status = "degraded"
+			}
+		}
+		c.JSON(200, gin.H{
+			"status":           status,
+			"service":          "public-api",
+			"partial_failures": publicAPIService.PartialFailures(),
+			"circuit_breakers": breakers,
+		})
+	})
+	router.GET("/debug/cache", func(c *gin.Context) {
+		c.JSON(200, gin.H{"user_cache": userCache.Stats()})
 	})
 	port := os.Getenv("PUBLIC_API_PORT")
 	if port == "" {
 		port = "8000"
 	}
-	log.Printf("Public API service starting on port %s", port)
-	log.Printf("User Service URL: %s", userServiceURL)
-	log.Printf("Listing Service URL: %s", listingServiceURL)
+	logger.Info("Public API service starting", "port", port, "user_service_url", config.UserService.URL, "listing_service_url", config.ListingService.URL)
 	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+		logging.Fatal(logger, "Failed to start server", "error", err)
 	}
 }
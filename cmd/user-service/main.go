@@ -1,48 +1,90 @@
 package main
 
 import (
-	"99-backend-exercise/internal/models"
 	"99-backend-exercise/internal/user"
 	"99-backend-exercise/pkg/database"
-	"log"
+	"99-backend-exercise/pkg/idempotency"
+	"99-backend-exercise/pkg/logging"
+	"99-backend-exercise/pkg/metrics"
+	"99-backend-exercise/pkg/tracing"
+	"context"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: No .env file found")
+	envErr := godotenv.Load()
+	logging.Setup(logging.NewConfig("user-service"))
+	logger := logging.Logger("server")
+	if envErr != nil {
+		logger.Warn("No .env file found")
 	}
+	tracerProvider, err := tracing.Setup(context.Background(), tracing.NewConfig("user-service"))
+	if err != nil {
+		logging.Fatal(logger, "Failed to set up tracing", "error", err)
+	}
+	defer func() {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
 	dbConfig := database.NewDatabaseConfig()
 	dbConn, err := database.Connect(dbConfig)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		logging.Fatal(logger, "Failed to connect to database", "error", err)
 	}
 	defer dbConn.Close()
-	if err := dbConn.AutoMigrate(&models.User{}); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	migrator, err := database.NewMigrator(dbConn, "user-service", user.Migrations())
+	if err != nil {
+		logging.Fatal(logger, "Failed to load migrations", "error", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.RunMigrateCommand(migrator, os.Args[2:]); err != nil {
+			logging.Fatal(logger, "Migration failed", "error", err)
+		}
+		return
+	}
+	if dbConfig.MigrateOnStart {
+		if _, err := migrator.Up(); err != nil {
+			logging.Fatal(logger, "Failed to migrate database", "error", err)
+		}
 	}
 	userRepo := user.NewRepository(dbConn.DB)
 	userService := user.NewService(userRepo)
 	userHandler := user.NewHandler(userService)
-	router := gin.Default()
+	router := gin.New()
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(otelgin.Middleware("user-service"))
+	router.Use(logging.AccessLogMiddleware(logging.Logger("http")))
+	router.Use(logging.RecoveryMiddleware(logger))
+	router.Use(metrics.Middleware("user-service"))
+	router.GET("/metrics", metrics.Handler())
 	v1 := router.Group("/")
+	v1.Use(idempotency.NewStore(dbConn.DB, "user_idempotency_keys", idempotency.NewConfig()).Middleware())
 	{
 		v1.GET("/users", userHandler.GetUsers)
 		v1.GET("/users/:id", userHandler.GetUserByID)
 		v1.POST("/users", userHandler.CreateUser)
+		v1.PATCH("/users/:id", userHandler.UpdateUser)
+		v1.DELETE("/users/:id", userHandler.DeleteUser)
 	}
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "user-service"})
+		dbHealth := dbConn.Health()
+		status, code := "ok", 200
+		if dbHealth.Status != "ok" {
+			status, code = "degraded", 503
+		}
+		c.JSON(code, gin.H{"status": status, "service": "user-service", "database": dbHealth})
 	})
 	port := os.Getenv("USER_SERVICE_PORT")
 	if port == "" {
 		port = "8001"
 	}
-	log.Printf("User service starting on port %s", port)
+	logger.Info("User service starting", "port", port)
 	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+		logging.Fatal(logger, "Failed to start server", "error", err)
 	}
 }
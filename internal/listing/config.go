@@ -0,0 +1,28 @@
+package listing
+import (
+	"os"
+	"strconv"
+)
+type Config struct {
+	VerifyOwner    bool
+	UserServiceURL string
+}
+func NewConfig() *Config {
+	return &Config{
+		VerifyOwner:    getEnvBool("LISTING_VERIFY_OWNER", false),
+		UserServiceURL: getEnv("USER_SERVICE_URL", "http://localhost:8001"),
+	}
+}
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,180 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"github.com/gin-gonic/gin"
+)
+type Handler struct {
+	listingService Service
+}
+func NewHandler(listingService Service) *Handler {
+	return &Handler{
+		listingService: listingService,
+	}
+}
+func (h *Handler) GetListings(c *gin.Context) {
+	var request models.GetListingsRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	if err := request.Validate(); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	listings, err := h.listingService.GetListings(c.Request.Context(), request)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get listings", err)
+		return
+	}
+	utils.RespondWithSuccess(c, listings)
+}
+func (h *Handler) CreateListing(c *gin.Context) {
+	var request models.CreateListingRequest
+	if err := c.ShouldBind(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	listing, err := h.listingService.CreateListing(c.Request.Context(), request)
+	if err != nil {
+		utils.RespondWithErrorCode(c, statusCodeForError(err), errorCodeForError(err), "Failed to create listing", err)
+		return
+	}
+	response := map[string]interface{}{
+		"listing": listing,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) GetListingByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	listing, err := h.listingService.GetListingByID(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get listing", err)
+		return
+	}
+	response := map[string]interface{}{
+		"listing": listing,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) UpdateListing(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	var request models.UpdateListingRequest
+	if err := c.ShouldBind(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	listing, err := h.listingService.UpdateListing(c.Request.Context(), id, request)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to update listing", err)
+		return
+	}
+	response := map[string]interface{}{
+		"listing": listing,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) DeleteListing(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	listing, err := h.listingService.DeleteListing(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to delete listing", err)
+		return
+	}
+	response := map[string]interface{}{
+		"listing": listing,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) TransitionListing(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	var request models.TransitionListingRequest
+	if err := c.ShouldBind(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	listing, err := h.listingService.TransitionListing(c.Request.Context(), id, request)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to transition listing", err)
+		return
+	}
+	response := map[string]interface{}{
+		"listing": listing,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) GetStatusTransitions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	transitions, err := h.listingService.GetStatusTransitions(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get status transitions", err)
+		return
+	}
+	response := map[string]interface{}{
+		"transitions": transitions,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) GetPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	history, err := h.listingService.GetPriceHistory(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get price history", err)
+		return
+	}
+	response := map[string]interface{}{
+		"price_history": history,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) Ping(c *gin.Context) {
+	c.String(http.StatusOK, "pong!")
+}
+func statusCodeForError(err error) int {
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, ErrListingNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, ErrInvalidStatusTransition) || errors.Is(err, ErrListingPriceChanged) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, ErrOwnerNotFound) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+func errorCodeForError(err error) string {
+	if errors.Is(err, ErrOwnerNotFound) {
+		return models.ErrorCodeUserNotFound
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+func newTestRouter(service Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(service)
+	router := gin.New()
+	router.GET("/listings", handler.GetListings)
+	router.POST("/listings", handler.CreateListing)
+	return router
+}
+func serve(router *gin.Engine, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+func TestHandlerGetListingsRejectsInvalidFilters(t *testing.T) {
+	router := newTestRouter(NewService(newTestRepository(t), nil))
+	tests := []string{
+		"price_min=5000&price_max=1000",
+		"created_from=2000&created_to=1000",
+		"price_min=-1",
+		"price_max=abc",
+		"user_id=abc",
+		"listing_type=lease",
+		"sort_by=title",
+		"sort_order=up",
+		"status=archived",
+	}
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			recorder := serve(router, httptest.NewRequest(http.MethodGet, "/listings?"+query, nil))
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("GET /listings?%s = %d %s, want 400", query, recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
+func TestHandlerGetListingsFilters(t *testing.T) {
+	repo := newTestRepository(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	create := func(userID int, listingType string, price int, age time.Duration) int {
+		listing := models.Listing{UserID: userID, ListingType: listingType, Price: price}
+		listing.CreatedAt = base.Add(-age)
+		return createTestListing(t, repo, listing).ID
+	}
+	cheapRent := create(1, "rent", 1000, 3*time.Hour)
+	midRent := create(2, "rent", 3000, 2*time.Hour)
+	midSale := create(1, "sale", 3000, time.Hour)
+	dearSale := create(2, "sale", 9000, 0)
+	router := newTestRouter(NewService(repo, nil))
+	micros := func(age time.Duration) int64 { return base.Add(-age).UnixMicro() }
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{query: "", want: []int{dearSale, midSale, midRent, cheapRent}},
+		{query: "listing_type=rent", want: []int{midRent, cheapRent}},
+		{query: "user_id=1", want: []int{midSale, cheapRent}},
+		{query: "price_min=3000", want: []int{dearSale, midSale, midRent}},
+		{query: "price_max=3000", want: []int{midSale, midRent, cheapRent}},
+		{query: "price_min=3000&price_max=3000", want: []int{midSale, midRent}},
+		{query: "price_min=2000&price_max=2000", want: []int{}},
+		{query: fmt.Sprintf("created_from=%d", micros(2*time.Hour)), want: []int{dearSale, midSale, midRent}},
+		{query: fmt.Sprintf("created_to=%d", micros(2*time.Hour)), want: []int{midRent, cheapRent}},
+		{query: "listing_type=sale&user_id=2&price_min=5000", want: []int{dearSale}},
+		{query: "sort_by=price&sort_order=asc", want: []int{cheapRent, midRent, midSale, dearSale}},
+		{query: "sort_by=price&listing_type=sale", want: []int{dearSale, midSale}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			recorder := serve(router, httptest.NewRequest(http.MethodGet, "/listings?"+tt.query, nil))
+			var response struct {
+				Data models.ListingListResponse `json:"data"`
+			}
+			if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &response) != nil {
+				t.Fatalf("GET /listings?%s = %d %s", tt.query, recorder.Code, recorder.Body)
+			}
+			got := []int{}
+			for _, listing := range response.Data.Listings {
+				got = append(got, listing.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || response.Data.Total != int64(len(tt.want)) {
+				t.Errorf("GET /listings?%s returned IDs %v (total %d), want %v", tt.query, got, response.Data.Total, tt.want)
+			}
+		})
+	}
+}
+func TestHandlerCreateListingAttributes(t *testing.T) {
+	router := newTestRouter(NewService(newTestRepository(t), nil))
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(router, request)
+	}
+	form := url.Values{
+		"user_id": {"1"}, "listing_type": {"rent"}, "price": {"4500"},
+		"title": {"Sunny flat"}, "city": {"Singapore"}, "postal_code": {"123456"},
+		"bedrooms": {"3"}, "floor_area": {"85.5"}, "property_type": {models.PropertyTypeCondo},
+	}
+	recorder := post(form)
+	var response struct {
+		Data struct {
+			Listing map[string]interface{} `json:"listing"`
+		} `json:"data"`
+	}
+	if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &response) != nil {
+		t.Fatalf("POST /listings = %d %s, want 200", recorder.Code, recorder.Body)
+	}
+	want := map[string]interface{}{"title": "Sunny flat", "city": "Singapore", "postal_code": "123456", "bedrooms": 3.0, "floor_area": 85.5, "property_type": "condo"}
+	for key, value := range want {
+		if response.Data.Listing[key] != value {
+			t.Errorf("listing[%q] = %v, want %v", key, response.Data.Listing[key], value)
+		}
+	}
+	var unset []string
+	for _, key := range []string{"description", "address", "bathrooms"} {
+		if _, ok := response.Data.Listing[key]; ok {
+			unset = append(unset, key)
+		}
+	}
+	sort.Strings(unset)
+	if len(unset) > 0 {
+		t.Errorf("unset attributes %v are present in the response, want them omitted", unset)
+	}
+	invalid := map[string]string{
+		"postal_code":   "12345",
+		"property_type": "castle",
+		"bedrooms":      "-1",
+		"floor_area":    "0",
+		"title":         "",
+	}
+	for field, value := range invalid {
+		t.Run("invalid "+field, func(t *testing.T) {
+			form := url.Values{"user_id": {"1"}, "listing_type": {"rent"}, "price": {"4500"}, field: {value}}
+			if recorder := post(form); recorder.Code != http.StatusBadRequest {
+				t.Fatalf("POST /listings with %s=%q = %d %s, want 400", field, value, recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
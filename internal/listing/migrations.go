@@ -0,0 +1,11 @@
+package listing
+import (
+	"embed"
+	"io/fs"
+)
+//go:embed migrations
+var migrationFiles embed.FS
+func Migrations() fs.FS {
+	migrations, _ := fs.Sub(migrationFiles, "migrations")
+	return migrations
+}
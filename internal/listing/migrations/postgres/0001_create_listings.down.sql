@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_listings_user_id;
+DROP TABLE IF EXISTS listings;
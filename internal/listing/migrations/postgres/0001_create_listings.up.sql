@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS listings (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	price BIGINT NOT NULL,
+	listing_type TEXT NOT NULL,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_listings_user_id ON listings (user_id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_listings_property_type;
+DROP INDEX IF EXISTS idx_listings_city;
+ALTER TABLE listings DROP COLUMN property_type;
+ALTER TABLE listings DROP COLUMN floor_area;
+ALTER TABLE listings DROP COLUMN bathrooms;
+ALTER TABLE listings DROP COLUMN bedrooms;
+ALTER TABLE listings DROP COLUMN postal_code;
+ALTER TABLE listings DROP COLUMN city;
+ALTER TABLE listings DROP COLUMN address;
+ALTER TABLE listings DROP COLUMN description;
+ALTER TABLE listings DROP COLUMN title;
@@ -0,0 +1,11 @@
+ALTER TABLE listings ADD COLUMN title VARCHAR(200);
+ALTER TABLE listings ADD COLUMN description TEXT;
+ALTER TABLE listings ADD COLUMN address VARCHAR(255);
+ALTER TABLE listings ADD COLUMN city VARCHAR(100);
+ALTER TABLE listings ADD COLUMN postal_code VARCHAR(6);
+ALTER TABLE listings ADD COLUMN bedrooms INTEGER;
+ALTER TABLE listings ADD COLUMN bathrooms INTEGER;
+ALTER TABLE listings ADD COLUMN floor_area DOUBLE PRECISION;
+ALTER TABLE listings ADD COLUMN property_type VARCHAR(20);
+CREATE INDEX IF NOT EXISTS idx_listings_city ON listings (city);
+CREATE INDEX IF NOT EXISTS idx_listings_property_type ON listings (property_type);
@@ -0,0 +1,12 @@
+ALTER TABLE listings ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE listings ADD COLUMN status_changed_at TIMESTAMPTZ;
+UPDATE listings SET status_changed_at = created_at WHERE status_changed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_status ON listings (status);
+CREATE TABLE IF NOT EXISTS listing_status_transitions (
+	id BIGSERIAL PRIMARY KEY,
+	listing_id BIGINT NOT NULL,
+	from_status VARCHAR(20) NOT NULL DEFAULT '',
+	to_status VARCHAR(20) NOT NULL,
+	created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_listing_status_transitions_listing_id ON listing_status_transitions (listing_id);
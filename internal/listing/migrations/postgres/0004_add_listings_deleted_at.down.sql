@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_listings_deleted_at;
+ALTER TABLE listings DROP COLUMN deleted_at;
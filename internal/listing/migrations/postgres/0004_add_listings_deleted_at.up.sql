@@ -0,0 +1,2 @@
+ALTER TABLE listings ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_listings_deleted_at ON listings (deleted_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_listing_price_history_listing_id;
+DROP TABLE IF EXISTS listing_price_history;
+ALTER TABLE listings DROP COLUMN price_changed_at;
+ALTER TABLE listings DROP COLUMN previous_price;
@@ -0,0 +1,10 @@
+ALTER TABLE listings ADD COLUMN previous_price BIGINT;
+ALTER TABLE listings ADD COLUMN price_changed_at TIMESTAMPTZ;
+CREATE TABLE IF NOT EXISTS listing_price_history (
+	id BIGSERIAL PRIMARY KEY,
+	listing_id BIGINT NOT NULL,
+	old_price BIGINT NOT NULL,
+	new_price BIGINT NOT NULL,
+	created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_listing_price_history_listing_id ON listing_price_history (listing_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS listing_idempotency_keys (
+	idempotency_key TEXT PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT,
+	body BYTEA,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_listing_idempotency_keys_created_at ON listing_idempotency_keys (created_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS listings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	price INTEGER NOT NULL,
+	listing_type TEXT NOT NULL,
+	created_at DATETIME,
+	updated_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_listings_user_id ON listings (user_id);
@@ -0,0 +1,11 @@
+ALTER TABLE listings ADD COLUMN title TEXT;
+ALTER TABLE listings ADD COLUMN description TEXT;
+ALTER TABLE listings ADD COLUMN address TEXT;
+ALTER TABLE listings ADD COLUMN city TEXT;
+ALTER TABLE listings ADD COLUMN postal_code TEXT;
+ALTER TABLE listings ADD COLUMN bedrooms INTEGER;
+ALTER TABLE listings ADD COLUMN bathrooms INTEGER;
+ALTER TABLE listings ADD COLUMN floor_area REAL;
+ALTER TABLE listings ADD COLUMN property_type TEXT;
+CREATE INDEX IF NOT EXISTS idx_listings_city ON listings (city);
+CREATE INDEX IF NOT EXISTS idx_listings_property_type ON listings (property_type);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_listing_status_transitions_listing_id;
+DROP TABLE IF EXISTS listing_status_transitions;
+DROP INDEX IF EXISTS idx_listings_status;
+ALTER TABLE listings DROP COLUMN status_changed_at;
+ALTER TABLE listings DROP COLUMN status;
@@ -0,0 +1,12 @@
+ALTER TABLE listings ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE listings ADD COLUMN status_changed_at DATETIME;
+UPDATE listings SET status_changed_at = created_at WHERE status_changed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_listings_status ON listings (status);
+CREATE TABLE IF NOT EXISTS listing_status_transitions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	listing_id INTEGER NOT NULL,
+	from_status TEXT NOT NULL DEFAULT '',
+	to_status TEXT NOT NULL,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_listing_status_transitions_listing_id ON listing_status_transitions (listing_id);
@@ -0,0 +1,10 @@
+ALTER TABLE listings ADD COLUMN previous_price INTEGER;
+ALTER TABLE listings ADD COLUMN price_changed_at DATETIME;
+CREATE TABLE IF NOT EXISTS listing_price_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	listing_id INTEGER NOT NULL,
+	old_price INTEGER NOT NULL,
+	new_price INTEGER NOT NULL,
+	created_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_listing_price_history_listing_id ON listing_price_history (listing_id);
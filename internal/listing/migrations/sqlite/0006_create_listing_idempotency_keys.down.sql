@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_listing_idempotency_keys_created_at;
+DROP TABLE IF EXISTS listing_idempotency_keys;
@@ -0,0 +1,69 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+// OwnerVerifier checks that the user a listing is created for exists and has
+// not been deleted.
+type OwnerVerifier interface {
+	VerifyOwner(ctx context.Context, userID int) error
+}
+type userServiceOwnerVerifier struct {
+	client         *http.Client
+	userServiceURL string
+}
+func NewOwnerVerifier(userServiceURL string) OwnerVerifier {
+	return &userServiceOwnerVerifier{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		userServiceURL: userServiceURL,
+	}
+}
+func (v *userServiceOwnerVerifier) VerifyOwner(ctx context.Context, userID int) error {
+	url := fmt.Sprintf("%s/users/%d", v.userServiceURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call user service: %w", err)
+	}
+	defer resp.Body.Close()
+	var envelope struct {
+		ErrorCode string `json:"error_code"`
+		Data      struct {
+			User *models.UserResponse `json:"user"`
+		} `json:"data"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&envelope)
+	// Only a 404 carrying the user_not_found code means the user is missing;
+	// any other failure says nothing about the user.
+	if resp.StatusCode == http.StatusNotFound && decodeErr == nil && envelope.ErrorCode == models.ErrorCodeUserNotFound {
+		return fmt.Errorf("%w: user %d does not exist", ErrOwnerNotFound, userID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode user service response: %w", decodeErr)
+	}
+	if envelope.Data.User == nil {
+		return fmt.Errorf("user service response is missing the user")
+	}
+	if envelope.Data.User.DeletedAt != nil {
+		return fmt.Errorf("%w: user %d has been deleted", ErrOwnerNotFound, userID)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+// startStubUserService answers GET /users/:id with the status and body
+// registered for the path, and with a 500 for any other path.
+func startStubUserService(t *testing.T, responses map[string]struct {
+	status int
+	body   string
+}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+func postListing(router http.Handler, userID string) *httptest.ResponseRecorder {
+	form := url.Values{"user_id": {userID}, "listing_type": {"rent"}, "price": {"4500"}}
+	request := httptest.NewRequest(http.MethodPost, "/listings", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+func TestHandlerCreateListingVerifiesOwner(t *testing.T) {
+	userService := startStubUserService(t, map[string]struct {
+		status int
+		body   string
+	}{
+		"/users/1": {http.StatusOK, `{"result":true,"version":"1","data":{"user":{"id":1,"name":"Ana","created_at":1700000000}}}`},
+		"/users/2": {http.StatusNotFound, `{"result":false,"version":"1","message":"Failed to get user","error":"user not found","error_code":"user_not_found","code":404}`},
+		"/users/3": {http.StatusOK, `{"result":true,"version":"1","data":{"user":{"id":3,"name":"Bo","created_at":1700000000,"deleted_at":1700000100}}}`},
+		"/users/4": {http.StatusNotFound, `404 page not found`},
+		"/users/5": {http.StatusOK, `{"result":true,"version":"1","data":{}}`},
+	})
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	tests := []struct {
+		name          string
+		url           string
+		userID        string
+		wantStatus    int
+		wantErrorCode string
+	}{
+		{name: "existing owner", url: userService.URL, userID: "1", wantStatus: http.StatusOK},
+		{name: "unknown owner", url: userService.URL, userID: "2", wantStatus: http.StatusUnprocessableEntity, wantErrorCode: models.ErrorCodeUserNotFound},
+		{name: "deleted owner", url: userService.URL, userID: "3", wantStatus: http.StatusUnprocessableEntity, wantErrorCode: models.ErrorCodeUserNotFound},
+		{name: "404 without error code", url: userService.URL, userID: "4", wantStatus: http.StatusInternalServerError},
+		{name: "response without user", url: userService.URL, userID: "5", wantStatus: http.StatusInternalServerError},
+		{name: "user service error", url: userService.URL, userID: "6", wantStatus: http.StatusInternalServerError},
+		{name: "user service down", url: down.URL, userID: "1", wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t)
+			router := newTestRouter(NewService(repo, NewOwnerVerifier(tt.url)))
+			recorder := postListing(router, tt.userID)
+			var response models.Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("POST /listings returned invalid JSON %s: %v", recorder.Body, err)
+			}
+			if recorder.Code != tt.wantStatus || response.ErrorCode != tt.wantErrorCode {
+				t.Fatalf("POST /listings = %d with error code %q, want %d with %q: %s", recorder.Code, response.ErrorCode, tt.wantStatus, tt.wantErrorCode, recorder.Body)
+			}
+			count, err := repo.Count(context.Background(), Filter{})
+			if err != nil {
+				t.Fatalf("Count() = %v", err)
+			}
+			if created := recorder.Code == http.StatusOK; (count == 1) != created {
+				t.Errorf("%d listings stored after a %d response", count, recorder.Code)
+			}
+		})
+	}
+}
+func TestHandlerCreateListingWithoutOwnerVerifier(t *testing.T) {
+	router := newTestRouter(NewService(newTestRepository(t), nil))
+	if recorder := postListing(router, "999"); recorder.Code != http.StatusOK {
+		t.Fatalf("POST /listings without an owner verifier = %d %s, want 200", recorder.Code, recorder.Body)
+	}
+}
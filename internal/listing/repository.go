@@ -0,0 +1,194 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"fmt"
+	"time"
+	"gorm.io/gorm"
+)
+type Filter struct {
+	UserID      *int
+	ListingType string
+	PriceMin    *int
+	PriceMax    *int
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Status      string
+}
+type Sort struct {
+	Column     string
+	Descending bool
+}
+type Repository interface {
+	GetAll(ctx context.Context, filter Filter, sort Sort, offset, limit int, cursor *models.Cursor) ([]models.Listing, error)
+	GetByID(ctx context.Context, id int) (*models.Listing, error)
+	Create(ctx context.Context, listing *models.Listing) error
+	Update(ctx context.Context, listing *models.Listing, priceChange *models.ListingPriceChange) error
+	Delete(ctx context.Context, listing *models.Listing) error
+	UpdateStatus(ctx context.Context, listing *models.Listing, fromStatus string) error
+	GetStatusTransitions(ctx context.Context, listingID int) ([]models.ListingStatusTransition, error)
+	GetPriceHistory(ctx context.Context, listingID int) ([]models.ListingPriceChange, error)
+	Count(ctx context.Context, filter Filter) (int64, error)
+}
+type repository struct {
+	db *gorm.DB
+}
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+func (r *repository) GetAll(ctx context.Context, filter Filter, sort Sort, offset, limit int, cursor *models.Cursor) ([]models.Listing, error) {
+	var listings []models.Listing
+	direction, comparison := "ASC", ">"
+	if sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	query := applyFilter(r.db.WithContext(ctx), filter).
+		Order(fmt.Sprintf("%s %s, id %s", sort.Column, direction, direction)).
+		Limit(limit)
+	if cursor != nil {
+		var value interface{} = cursor.Value
+		if sort.Column == models.SortByCreatedAt {
+			value = time.Unix(0, cursor.Value).UTC()
+		}
+		condition := fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", sort.Column, comparison)
+		query = query.Where(condition, value, value, cursor.ID)
+	} else {
+		query = query.Offset(offset)
+	}
+	err := query.Find(&listings).Error
+	return listings, err
+}
+func (r *repository) GetByID(ctx context.Context, id int) (*models.Listing, error) {
+	var listing models.Listing
+	err := r.db.WithContext(ctx).First(&listing, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+func (r *repository) Create(ctx context.Context, listing *models.Listing) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(listing).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ListingStatusTransition{
+			ListingID: listing.ID,
+			ToStatus:  listing.Status,
+			CreatedAt: *listing.StatusChangedAt,
+		}).Error
+	})
+}
+func (r *repository) Update(ctx context.Context, listing *models.Listing, priceChange *models.ListingPriceChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Updating by ID rather than saving the struct keeps a listing deleted
+		// in the meantime from being inserted again or revived. Matching the
+		// price that was read keeps a concurrent price change from being
+		// overwritten or recorded in the history against a stale old price.
+		readPrice := listing.Price
+		if priceChange != nil {
+			readPrice = priceChange.OldPrice
+		}
+		listing.UpdatedAt = tx.NowFunc()
+		result := tx.Model(&models.Listing{}).Where("id = ? AND deleted_at IS NULL AND price = ?", listing.ID, readPrice).Updates(map[string]interface{}{
+			"price":            listing.Price,
+			"previous_price":   listing.PreviousPrice,
+			"price_changed_at": listing.PriceChangedAt,
+			"title":            listing.Title,
+			"description":      listing.Description,
+			"address":          listing.Address,
+			"city":             listing.City,
+			"postal_code":      listing.PostalCode,
+			"bedrooms":         listing.Bedrooms,
+			"bathrooms":        listing.Bathrooms,
+			"floor_area":       listing.FloorArea,
+			"property_type":    listing.PropertyType,
+			"updated_at":       listing.UpdatedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.Listing{}).Where("id = ?", listing.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			return ErrListingPriceChanged
+		}
+		if priceChange == nil {
+			return nil
+		}
+		return tx.Create(priceChange).Error
+	})
+}
+func (r *repository) Delete(ctx context.Context, listing *models.Listing) error {
+	result := r.db.WithContext(ctx).Delete(listing)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return r.db.WithContext(ctx).Unscoped().First(listing, listing.ID).Error
+}
+func (r *repository) UpdateStatus(ctx context.Context, listing *models.Listing, fromStatus string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(listing).Where("status = ?", fromStatus).Updates(map[string]interface{}{
+			"status":            listing.Status,
+			"status_changed_at": listing.StatusChangedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidStatusTransition
+		}
+		return tx.Create(&models.ListingStatusTransition{
+			ListingID:  listing.ID,
+			FromStatus: fromStatus,
+			ToStatus:   listing.Status,
+			CreatedAt:  *listing.StatusChangedAt,
+		}).Error
+	})
+}
+func (r *repository) GetStatusTransitions(ctx context.Context, listingID int) ([]models.ListingStatusTransition, error) {
+	var transitions []models.ListingStatusTransition
+	err := r.db.WithContext(ctx).Where("listing_id = ?", listingID).Order("created_at ASC, id ASC").Find(&transitions).Error
+	return transitions, err
+}
+func (r *repository) GetPriceHistory(ctx context.Context, listingID int) ([]models.ListingPriceChange, error) {
+	var history []models.ListingPriceChange
+	err := r.db.WithContext(ctx).Where("listing_id = ?", listingID).Order("created_at DESC, id DESC").Find(&history).Error
+	return history, err
+}
+func (r *repository) Count(ctx context.Context, filter Filter) (int64, error) {
+	var count int64
+	err := applyFilter(r.db.WithContext(ctx).Model(&models.Listing{}), filter).Count(&count).Error
+	return count, err
+}
+func applyFilter(query *gorm.DB, filter Filter) *gorm.DB {
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.ListingType != "" {
+		query = query.Where("listing_type = ?", filter.ListingType)
+	}
+	if filter.PriceMin != nil {
+		query = query.Where("price >= ?", *filter.PriceMin)
+	}
+	if filter.PriceMax != nil {
+		query = query.Where("price <= ?", *filter.PriceMax)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedTo)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return query
+}
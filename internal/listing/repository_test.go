@@ -0,0 +1,289 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/database/databasetest"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"gorm.io/gorm"
+)
+func newTestRepository(t *testing.T) *repository {
+	return &repository{db: databasetest.NewSQLite(t, "listing-service", Migrations()).DB}
+}
+func createTestListing(t *testing.T, repo *repository, listing models.Listing) models.Listing {
+	t.Helper()
+	if listing.UserID == 0 {
+		listing.UserID = 1
+	}
+	if listing.ListingType == "" {
+		listing.ListingType = "sale"
+	}
+	if listing.Price == 0 {
+		listing.Price = 1000
+	}
+	if listing.Status == "" {
+		listing.Status = models.ListingStatusActive
+	}
+	if listing.StatusChangedAt == nil {
+		now := time.Now().UTC()
+		listing.StatusChangedAt = &now
+	}
+	if err := repo.Create(context.Background(), &listing); err != nil {
+		t.Fatalf("failed to create listing: %v", err)
+	}
+	return listing
+}
+func TestRepositoryUpdateStatusRejectsStaleStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	first, second := created, created
+	now := time.Now()
+	first.Status, first.StatusChangedAt = models.ListingStatusUnderOffer, &now
+	if err := repo.UpdateStatus(ctx, &first, models.ListingStatusActive); err != nil {
+		t.Fatalf("first UpdateStatus() = %v, want nil", err)
+	}
+	second.Status, second.StatusChangedAt = models.ListingStatusExpired, &now
+	if err := repo.UpdateStatus(ctx, &second, models.ListingStatusActive); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Fatalf("stale UpdateStatus() = %v, want ErrInvalidStatusTransition", err)
+	}
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ListingStatusUnderOffer {
+		t.Errorf("status = %s, want %s", stored.Status, models.ListingStatusUnderOffer)
+	}
+	transitions, err := repo.GetStatusTransitions(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 || transitions[1].ToStatus != models.ListingStatusUnderOffer {
+		t.Errorf("transitions = %+v, want the initial status and one transition to %s", transitions, models.ListingStatusUnderOffer)
+	}
+}
+func TestRepositoryUpdateStatusConcurrentConflict(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	targets := []string{models.ListingStatusUnderOffer, models.ListingStatusExpired, models.ListingStatusDraft}
+	const workers = 9
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		errs      = make([]error, workers)
+		successes = make([]string, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every worker read the listing while it was still active.
+			listing := created
+			now := time.Now()
+			listing.Status, listing.StatusChangedAt = targets[i%len(targets)], &now
+			<-start
+			errs[i] = repo.UpdateStatus(ctx, &listing, models.ListingStatusActive)
+			if errs[i] == nil {
+				successes[i] = listing.Status
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	var won string
+	for i, err := range errs {
+		switch {
+		case err == nil && won == "":
+			won = successes[i]
+		case err == nil:
+			t.Errorf("worker %d also moved the listing out of active", i)
+		case !errors.Is(err, ErrInvalidStatusTransition):
+			t.Errorf("worker %d: UpdateStatus() = %v, want ErrInvalidStatusTransition", i, err)
+		}
+	}
+	if won == "" {
+		t.Fatal("no worker managed to change the status")
+	}
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != won {
+		t.Errorf("status = %s, want %s set by the winning worker", stored.Status, won)
+	}
+	transitions, err := repo.GetStatusTransitions(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 {
+		t.Errorf("got %d transitions, want the initial status and exactly one change", len(transitions))
+	}
+}
+func TestRepositorySoftDeleteHidesListings(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	kept := createTestListing(t, repo, models.Listing{})
+	deleted := createTestListing(t, repo, models.Listing{})
+	if err := repo.Delete(ctx, &deleted); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Error("Delete() did not return the listing with deleted_at set")
+	}
+	if _, err := repo.GetByID(ctx, deleted.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID(deleted) = %v, want gorm.ErrRecordNotFound", err)
+	}
+	listings, err := repo.GetAll(ctx, Filter{}, Sort{Column: models.SortByCreatedAt}, 0, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listings) != 1 || listings[0].ID != kept.ID {
+		t.Errorf("GetAll() = %+v, want only listing %d", listings, kept.ID)
+	}
+	if count, err := repo.Count(ctx, Filter{}); count != 1 || err != nil {
+		t.Errorf("Count() = %d, %v, want 1", count, err)
+	}
+}
+func TestRepositoryDeleteTwice(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	// Both callers read the listing before either deleted it.
+	first, second := created, created
+	if err := repo.Delete(ctx, &first); err != nil {
+		t.Fatalf("first Delete() = %v", err)
+	}
+	if err := repo.Delete(ctx, &second); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("second Delete() = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+func TestRepositoryUpdateMissingListing(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	deleted := createTestListing(t, repo, models.Listing{})
+	stale := deleted
+	if err := repo.Delete(ctx, &deleted); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	stale.Price = 2000
+	if err := repo.Update(ctx, &stale, nil); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update(deleted) = %v, want gorm.ErrRecordNotFound", err)
+	}
+	missing := models.Listing{ID: deleted.ID + 1, UserID: 1, ListingType: "sale", Price: 1000}
+	if err := repo.Update(ctx, &missing, nil); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update(missing) = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if _, err := repo.GetByID(ctx, deleted.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID(deleted) after Update() = %v, want the listing to stay deleted", err)
+	}
+	var stored []models.Listing
+	if err := repo.db.Unscoped().Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Price != 1000 {
+		t.Errorf("stored listings = %+v, want only the deleted listing, unchanged", stored)
+	}
+}
+func TestRepositoryUpdateKeepsStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	stale := created
+	now := time.Now()
+	created.Status, created.StatusChangedAt = models.ListingStatusUnderOffer, &now
+	if err := repo.UpdateStatus(ctx, &created, models.ListingStatusActive); err != nil {
+		t.Fatalf("UpdateStatus() = %v", err)
+	}
+	title := "Renovated"
+	stale.Title = &title
+	if err := repo.Update(ctx, &stale, nil); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ListingStatusUnderOffer || stored.Title == nil || *stored.Title != title {
+		t.Errorf("stored listing has status %s and title %v, want %s and %q", stored.Status, stored.Title, models.ListingStatusUnderOffer, title)
+	}
+}
+func TestRepositoryUpdateRejectsStalePrice(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	changePrice := func(listing models.Listing, price int) error {
+		change := &models.ListingPriceChange{ListingID: listing.ID, OldPrice: listing.Price, NewPrice: price, CreatedAt: time.Now()}
+		listing.PreviousPrice, listing.PriceChangedAt, listing.Price = &change.OldPrice, &change.CreatedAt, price
+		return repo.Update(ctx, &listing, change)
+	}
+	// Both price changes and the title edit were read at the original price.
+	if err := changePrice(created, 1200); err != nil {
+		t.Fatalf("first price change: Update() = %v", err)
+	}
+	if err := changePrice(created, 900); !errors.Is(err, ErrListingPriceChanged) {
+		t.Fatalf("stale price change: Update() = %v, want ErrListingPriceChanged", err)
+	}
+	title := "Renovated"
+	stale := created
+	stale.Title = &title
+	if err := repo.Update(ctx, &stale, nil); !errors.Is(err, ErrListingPriceChanged) {
+		t.Fatalf("stale edit: Update() = %v, want ErrListingPriceChanged", err)
+	}
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Price != 1200 || stored.Title != nil {
+		t.Errorf("stored listing has price %d and title %v, want 1200 and no title", stored.Price, stored.Title)
+	}
+	history, err := repo.GetPriceHistory(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].OldPrice != 1000 || history[0].NewPrice != 1200 {
+		t.Errorf("price history = %+v, want only the change from 1000 to 1200", history)
+	}
+}
+func TestRepositoryUpdatePriceConcurrently(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	const workers = 8
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, workers)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			listing := created
+			change := &models.ListingPriceChange{ListingID: listing.ID, OldPrice: listing.Price, NewPrice: 2000 + i, CreatedAt: time.Now()}
+			listing.Price = change.NewPrice
+			<-start
+			errs[i] = repo.Update(ctx, &listing, change)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case !errors.Is(err, ErrListingPriceChanged):
+			t.Errorf("worker %d: Update() = %v, want ErrListingPriceChanged", i, err)
+		}
+	}
+	history, err := repo.GetPriceHistory(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if won != 1 || len(history) != 1 || history[0].OldPrice != 1000 {
+		t.Errorf("%d updates succeeded with price history %+v, want one change from 1000", won, history)
+	}
+}
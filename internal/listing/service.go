@@ -0,0 +1,257 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"gorm.io/gorm"
+)
+type Service interface {
+	GetListings(ctx context.Context, request models.GetListingsRequest) (*models.ListingListResponse, error)
+	GetListingByID(ctx context.Context, id int) (*models.ListingResponse, error)
+	CreateListing(ctx context.Context, request models.CreateListingRequest) (*models.ListingResponse, error)
+	UpdateListing(ctx context.Context, id int, request models.UpdateListingRequest) (*models.ListingResponse, error)
+	DeleteListing(ctx context.Context, id int) (*models.ListingResponse, error)
+	TransitionListing(ctx context.Context, id int, request models.TransitionListingRequest) (*models.ListingResponse, error)
+	GetStatusTransitions(ctx context.Context, id int) ([]models.ListingStatusTransitionResponse, error)
+	GetPriceHistory(ctx context.Context, id int) ([]models.ListingPriceChangeResponse, error)
+}
+var (
+	ErrListingNotFound         = errors.New("listing not found")
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrOwnerNotFound           = errors.New("listing owner not found")
+	ErrListingPriceChanged     = errors.New("listing price changed concurrently")
+)
+type service struct {
+	listingRepo   Repository
+	ownerVerifier OwnerVerifier
+}
+// NewService creates the listing service. ownerVerifier may be nil, in which
+// case listings are created without checking that their owner exists.
+func NewService(listingRepo Repository, ownerVerifier OwnerVerifier) Service {
+	return &service{
+		listingRepo:   listingRepo,
+		ownerVerifier: ownerVerifier,
+	}
+}
+func (s *service) GetListings(ctx context.Context, request models.GetListingsRequest) (*models.ListingListResponse, error) {
+	if request.IsCursorMode() {
+		return s.getListingsAfterCursor(ctx, request)
+	}
+	filter, sort := newFilter(request), newSort(request)
+	offset := request.GetOffset()
+	limit := request.GetPageSize()
+	listings, err := s.listingRepo.GetAll(ctx, filter, sort, offset, limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.listingRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ListingListResponse{
+		Listings:           toListingResponses(listings),
+		PaginationResponse: models.NewPaginationResponse(request.PaginationRequest, total),
+	}, nil
+}
+func (s *service) getListingsAfterCursor(ctx context.Context, request models.GetListingsRequest) (*models.ListingListResponse, error) {
+	cursor, err := models.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	sortKey := request.SortKey()
+	if cursor != nil && cursor.Sort != sortKey {
+		return nil, models.ErrInvalidCursor
+	}
+	filter, sort := newFilter(request), newSort(request)
+	limit := request.GetLimit()
+	listings, err := s.listingRepo.GetAll(ctx, filter, sort, 0, limit+1, cursor)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.listingRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	nextCursor := ""
+	if len(listings) > limit {
+		listings = listings[:limit]
+		last := listings[limit-1]
+		value := last.CreatedAt.UnixNano()
+		if sort.Column == models.SortByPrice {
+			value = int64(last.Price)
+		}
+		nextCursor = models.EncodeCursor(models.Cursor{Sort: sortKey, Value: value, ID: last.ID})
+	}
+	return &models.ListingListResponse{
+		Listings:           toListingResponses(listings),
+		PaginationResponse: models.NewCursorPaginationResponse(request.PaginationRequest, total, nextCursor),
+	}, nil
+}
+func newFilter(request models.GetListingsRequest) Filter {
+	filter := Filter{
+		UserID:      request.UserID,
+		ListingType: request.ListingType,
+		PriceMin:    request.PriceMin,
+		PriceMax:    request.PriceMax,
+		Status:      request.Status,
+	}
+	if request.CreatedFrom != nil {
+		createdFrom := time.UnixMicro(*request.CreatedFrom).UTC()
+		filter.CreatedFrom = &createdFrom
+	}
+	if request.CreatedTo != nil {
+		createdTo := time.UnixMicro(*request.CreatedTo).UTC()
+		filter.CreatedTo = &createdTo
+	}
+	return filter
+}
+func newSort(request models.GetListingsRequest) Sort {
+	return Sort{
+		Column:     request.GetSortBy(),
+		Descending: request.GetSortOrder() == models.SortOrderDesc,
+	}
+}
+func toListingResponses(listings []models.Listing) []models.ListingResponse {
+	responses := make([]models.ListingResponse, len(listings))
+	for i, listing := range listings {
+		responses[i] = listing.ToResponse()
+	}
+	return responses
+}
+func (s *service) GetListingByID(ctx context.Context, id int) (*models.ListingResponse, error) {
+	listing, err := s.getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := listing.ToResponse()
+	return &response, nil
+}
+func (s *service) CreateListing(ctx context.Context, request models.CreateListingRequest) (*models.ListingResponse, error) {
+	if s.ownerVerifier != nil {
+		if err := s.ownerVerifier.VerifyOwner(ctx, request.UserID); err != nil {
+			return nil, err
+		}
+	}
+	status := request.Status
+	if status == "" {
+		status = models.ListingStatusActive
+	}
+	now := time.Now().UTC()
+	listing := &models.Listing{
+		UserID:            request.UserID,
+		ListingType:       request.ListingType,
+		Price:             request.Price,
+		ListingAttributes: request.ListingAttributes,
+		Status:            status,
+		StatusChangedAt:   &now,
+	}
+	err := s.listingRepo.Create(ctx, listing)
+	if err != nil {
+		return nil, err
+	}
+	response := listing.ToResponse()
+	return &response, nil
+}
+func (s *service) UpdateListing(ctx context.Context, id int, request models.UpdateListingRequest) (*models.ListingResponse, error) {
+	listing, err := s.getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var priceChange *models.ListingPriceChange
+	if request.Price != nil && *request.Price != listing.Price {
+		now := time.Now().UTC()
+		priceChange = &models.ListingPriceChange{
+			ListingID: listing.ID,
+			OldPrice:  listing.Price,
+			NewPrice:  *request.Price,
+			CreatedAt: now,
+		}
+		listing.PreviousPrice = &priceChange.OldPrice
+		listing.PriceChangedAt = &now
+		listing.Price = *request.Price
+	}
+	listing.ListingAttributes.Apply(request.ListingAttributes)
+	err = s.listingRepo.Update(ctx, listing, priceChange)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := listing.ToResponse()
+	return &response, nil
+}
+func (s *service) DeleteListing(ctx context.Context, id int) (*models.ListingResponse, error) {
+	listing, err := s.getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.listingRepo.Delete(ctx, listing)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	response := listing.ToResponse()
+	return &response, nil
+}
+func (s *service) TransitionListing(ctx context.Context, id int, request models.TransitionListingRequest) (*models.ListingResponse, error) {
+	listing, err := s.getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fromStatus := listing.Status
+	if !models.CanTransitionListingStatus(listing.ListingType, fromStatus, request.Status) {
+		return nil, fmt.Errorf("%w: %s listing cannot move from %s to %s", ErrInvalidStatusTransition, listing.ListingType, fromStatus, request.Status)
+	}
+	now := time.Now().UTC()
+	listing.Status = request.Status
+	listing.StatusChangedAt = &now
+	if err := s.listingRepo.UpdateStatus(ctx, listing, fromStatus); err != nil {
+		return nil, err
+	}
+	listing, err = s.getListing(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := listing.ToResponse()
+	return &response, nil
+}
+func (s *service) GetStatusTransitions(ctx context.Context, id int) ([]models.ListingStatusTransitionResponse, error) {
+	if _, err := s.getListing(ctx, id); err != nil {
+		return nil, err
+	}
+	transitions, err := s.listingRepo.GetStatusTransitions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.ListingStatusTransitionResponse, len(transitions))
+	for i, transition := range transitions {
+		responses[i] = transition.ToResponse()
+	}
+	return responses, nil
+}
+func (s *service) GetPriceHistory(ctx context.Context, id int) ([]models.ListingPriceChangeResponse, error) {
+	if _, err := s.getListing(ctx, id); err != nil {
+		return nil, err
+	}
+	history, err := s.listingRepo.GetPriceHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.ListingPriceChangeResponse, len(history))
+	for i, priceChange := range history {
+		responses[i] = priceChange.ToResponse()
+	}
+	return responses, nil
+}
+func (s *service) getListing(ctx context.Context, id int) (*models.Listing, error) {
+	listing, err := s.listingRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrListingNotFound
+	}
+	return listing, err
+}
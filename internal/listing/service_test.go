@@ -0,0 +1,183 @@
+package listing
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+// createKeysetListings creates listings whose prices and creation times tie in
+// groups, so that only the ID tie-breaker makes the order total.
+func createKeysetListings(t *testing.T, repo *repository) []models.Listing {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var listings []models.Listing
+	for i := 0; i < 11; i++ {
+		listing := models.Listing{Price: 1000 * (1 + i%3)}
+		listing.CreatedAt = base.Add(time.Duration(i/4) * time.Millisecond)
+		listings = append(listings, createTestListing(t, repo, listing))
+	}
+	return listings
+}
+// pageThroughListings follows next cursors from the first page to the last and
+// returns the IDs in the order they were served.
+func pageThroughListings(t *testing.T, svc Service, request models.GetListingsRequest) []int {
+	t.Helper()
+	var ids []int
+	for page := 0; ; page++ {
+		if page > 20 {
+			t.Fatal("pagination did not terminate")
+		}
+		response, err := svc.GetListings(context.Background(), request)
+		if err != nil {
+			t.Fatalf("GetListings(cursor %q) = %v", request.Cursor, err)
+		}
+		if len(response.Listings) > request.Limit {
+			t.Fatalf("got %d listings, want at most %d", len(response.Listings), request.Limit)
+		}
+		for _, listing := range response.Listings {
+			ids = append(ids, listing.ID)
+		}
+		if response.HasNext != (response.NextCursor != "") {
+			t.Fatalf("has_next = %v with next_cursor %q", response.HasNext, response.NextCursor)
+		}
+		if !response.HasNext {
+			return ids
+		}
+		request.Cursor = response.NextCursor
+	}
+}
+func TestServiceGetListingsKeysetPagination(t *testing.T) {
+	repo := newTestRepository(t)
+	listings := createKeysetListings(t, repo)
+	// Listings excluded by the filter share the same sort values; the cursor
+	// condition must not let them back in.
+	for _, listing := range listings[:3] {
+		excluded := models.Listing{ListingType: "rent", Price: listing.Price}
+		excluded.CreatedAt = listing.CreatedAt
+		createTestListing(t, repo, excluded)
+	}
+	svc := NewService(repo, nil)
+	tests := []struct {
+		sortBy, sortOrder string
+		value             func(models.Listing) int64
+	}{
+		{models.SortByPrice, models.SortOrderAsc, func(l models.Listing) int64 { return int64(l.Price) }},
+		{models.SortByPrice, models.SortOrderDesc, func(l models.Listing) int64 { return int64(l.Price) }},
+		{models.SortByCreatedAt, models.SortOrderAsc, func(l models.Listing) int64 { return l.CreatedAt.UnixNano() }},
+		{models.SortByCreatedAt, models.SortOrderDesc, func(l models.Listing) int64 { return l.CreatedAt.UnixNano() }},
+	}
+	for _, tt := range tests {
+		// Expected order: the sort column, then ID, both in the requested direction.
+		expected := append([]models.Listing(nil), listings...)
+		descending := tt.sortOrder == models.SortOrderDesc
+		sort.Slice(expected, func(i, j int) bool {
+			a, b := expected[i], expected[j]
+			if tt.value(a) != tt.value(b) {
+				return (tt.value(a) < tt.value(b)) != descending
+			}
+			return (a.ID < b.ID) != descending
+		})
+		var want []int
+		for _, listing := range expected {
+			want = append(want, listing.ID)
+		}
+		for _, limit := range []int{1, 2, 3, 4, 11, 12} {
+			request := models.GetListingsRequest{}
+			request.SortBy, request.SortOrder, request.Limit = tt.sortBy, tt.sortOrder, limit
+			request.ListingType = "sale"
+			got := pageThroughListings(t, svc, request)
+			if len(got) != len(want) {
+				t.Fatalf("%s limit %d: got IDs %v, want %v", request.SortKey(), limit, got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("%s limit %d: got IDs %v, want %v", request.SortKey(), limit, got, want)
+				}
+			}
+		}
+	}
+}
+func TestServiceGetListingsRejectsInvalidCursors(t *testing.T) {
+	repo := newTestRepository(t)
+	createKeysetListings(t, repo)
+	svc := NewService(repo, nil)
+	request := models.GetListingsRequest{}
+	request.SortBy, request.SortOrder, request.Limit = models.SortByPrice, models.SortOrderAsc, 2
+	first, err := svc.GetListings(context.Background(), request)
+	if err != nil || first.NextCursor == "" {
+		t.Fatalf("GetListings() = %+v, %v, want a next cursor", first, err)
+	}
+	tests := []struct {
+		name      string
+		sortOrder string
+		cursor    string
+	}{
+		{name: "cursor for another sort order", sortOrder: models.SortOrderDesc, cursor: first.NextCursor},
+		{name: "cursor without a sort", sortOrder: models.SortOrderAsc, cursor: models.EncodeCursor(models.Cursor{Value: 1000, ID: 1})},
+		{name: "malformed cursor", sortOrder: models.SortOrderAsc, cursor: "garbage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := request
+			request.SortOrder, request.Cursor = tt.sortOrder, tt.cursor
+			if _, err := svc.GetListings(context.Background(), request); !errors.Is(err, models.ErrInvalidCursor) {
+				t.Fatalf("GetListings() = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+func TestServiceDeleteListingTwice(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	created := createTestListing(t, repo, models.Listing{})
+	svc := NewService(repo, nil)
+	if _, err := svc.DeleteListing(ctx, created.ID); err != nil {
+		t.Fatalf("first DeleteListing() = %v", err)
+	}
+	if _, err := svc.DeleteListing(ctx, created.ID); !errors.Is(err, ErrListingNotFound) {
+		t.Fatalf("second DeleteListing() = %v, want ErrListingNotFound", err)
+	}
+	if _, err := svc.GetListingByID(ctx, created.ID); !errors.Is(err, ErrListingNotFound) {
+		t.Fatalf("GetListingByID(deleted) = %v, want ErrListingNotFound", err)
+	}
+}
+func TestServiceGetListingsOutsideUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*60*60)
+	t.Cleanup(func() { time.Local = local })
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	var ids []int
+	for i := 0; i < 4; i++ {
+		ids = append(ids, createTestListing(t, repo, models.Listing{}).ID)
+	}
+	// A listing written elsewhere in UTC must sort among those created here.
+	written := models.Listing{}
+	written.CreatedAt = time.Now().Add(time.Hour).UTC()
+	ids = append(ids, createTestListing(t, repo, written).ID)
+	svc := NewService(repo, nil)
+	got := pageThroughListings(t, svc, models.GetListingsRequest{PaginationRequest: models.PaginationRequest{Limit: 2}})
+	want := []int{ids[4], ids[3], ids[2], ids[1], ids[0]}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("paged through %v, want %v", got, want)
+	}
+	middle, err := repo.GetByID(ctx, ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, to := middle.CreatedAt.UnixMicro(), middle.CreatedAt.Add(time.Minute).UnixMicro()
+	response, err := svc.GetListings(ctx, models.GetListingsRequest{ListingFilterRequest: models.ListingFilterRequest{CreatedFrom: &from, CreatedTo: &to}})
+	if err != nil {
+		t.Fatalf("GetListings() = %v", err)
+	}
+	got = nil
+	for _, listing := range response.Listings {
+		got = append(got, listing.ID)
+	}
+	if want := []int{ids[3], ids[2]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("created range returned %v, want %v", got, want)
+	}
+}
@@ -1,19 +1,33 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
+const ResponseVersion = "1"
+
+// Error codes give clients a stable, machine-readable reason for a failure.
+const (
+	ErrorCodeUserNotFound = "user_not_found"
+)
+
 type Response struct {
-	Result  bool        `json:"result"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   interface{} `json:"error,omitempty"`
-	Code    int         `json:"code,omitempty"`
+	Result    bool        `json:"result"`
+	Version   string      `json:"version"`
+	Message   string      `json:"message,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     interface{} `json:"error,omitempty"`
+	ErrorCode string      `json:"error_code,omitempty"`
+	Code      int         `json:"code,omitempty"`
 }
 type PaginationRequest struct {
-	PageNum  int `form:"page_num" json:"page_num" binding:"min=1"`
-	PageSize int `form:"page_size" json:"page_size" binding:"min=1,max=100"`
+	PageNum  int    `form:"page_num" json:"page_num" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" json:"page_size" binding:"omitempty,min=1,max=100"`
+	Cursor   string `form:"cursor" json:"cursor,omitempty"`
+	Limit    int    `form:"limit" json:"limit,omitempty" binding:"omitempty,min=1,max=100"`
 }
 
 func (p *PaginationRequest) GetPageNum() int {
@@ -31,6 +45,77 @@ func (p *PaginationRequest) GetPageSize() int {
 func (p *PaginationRequest) GetOffset() int {
 	return (p.GetPageNum() - 1) * p.GetPageSize()
 }
+func (p *PaginationRequest) IsCursorMode() bool {
+	return p.Cursor != "" || p.Limit > 0
+}
+func (p *PaginationRequest) GetLimit() int {
+	if p.Limit <= 0 {
+		return p.GetPageSize()
+	}
+	return p.Limit
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a keyset page: the sort it was issued for, the
+// value of the sort column (timestamps in Unix nanoseconds) and the row ID as a
+// tie-breaker.
+type Cursor struct {
+	Sort  string `json:"s,omitempty"`
+	Value int64  `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+func DecodeCursor(encoded string) (*Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+type PaginationResponse struct {
+	Total      int64  `json:"total"`
+	PageNum    int    `json:"page_num,omitempty"`
+	PageSize   int    `json:"page_size"`
+	TotalPages int    `json:"total_pages"`
+	HasNext    bool   `json:"has_next"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func NewPaginationResponse(request PaginationRequest, total int64) PaginationResponse {
+	pageNum := request.GetPageNum()
+	pageSize := request.GetPageSize()
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return PaginationResponse{
+		Total:      total,
+		PageNum:    pageNum,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		HasNext:    pageNum < totalPages,
+	}
+}
+func NewCursorPaginationResponse(request PaginationRequest, total int64, nextCursor string) PaginationResponse {
+	limit := request.GetLimit()
+	return PaginationResponse{
+		Total:      total,
+		PageSize:   limit,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+		HasNext:    nextCursor != "",
+		NextCursor: nextCursor,
+	}
+}
 
 type Timestamp struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
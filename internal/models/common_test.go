@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Sort: "created_at_desc", Value: 1700000000123456789, ID: 1},
+		{Sort: "price_asc", Value: 0, ID: 42},
+		{Sort: "created_at_asc", Value: -1, ID: 7},
+		{Value: 99, ID: 2147483647},
+	}
+	for _, want := range tests {
+		encoded := EncodeCursor(want)
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("EncodeCursor(%+v) = %q, want an unpadded URL-safe string", want, encoded)
+		}
+		got, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(EncodeCursor(%+v)) = %v", want, err)
+		}
+		if *got != want {
+			t.Errorf("DecodeCursor(EncodeCursor(%+v)) = %+v", want, *got)
+		}
+	}
+}
+
+func TestDecodeCursorEmpty(t *testing.T) {
+	cursor, err := DecodeCursor("")
+	if cursor != nil || err != nil {
+		t.Fatalf(`DecodeCursor("") = %v, %v, want nil, nil`, cursor, err)
+	}
+}
+
+func TestDecodeCursorRejectsMalformedCursors(t *testing.T) {
+	encode := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+	valid := EncodeCursor(Cursor{Sort: "price_asc", Value: 1000, ID: 5})
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "not base64", encoded: "not a cursor!"},
+		{name: "padded", encoded: valid + "="},
+		{name: "truncated", encoded: valid[:len(valid)-3]},
+		{name: "flipped character", encoded: "X" + valid[1:]},
+		{name: "not JSON", encoded: encode("price_asc:1000:5")},
+		{name: "JSON array", encoded: encode(`[1000,5]`)},
+		{name: "wrong value type", encoded: encode(`{"s":"price_asc","v":"1000","id":5}`)},
+		{name: "missing ID", encoded: encode(`{"s":"price_asc","v":1000}`)},
+		{name: "zero ID", encoded: encode(`{"s":"price_asc","v":1000,"id":0}`)},
+		{name: "negative ID", encoded: encode(`{"s":"price_asc","v":1000,"id":-5}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(tt.encoded)
+			if !errors.Is(err, ErrInvalidCursor) || cursor != nil {
+				t.Fatalf("DecodeCursor(%q) = %+v, %v, want ErrInvalidCursor", tt.encoded, cursor, err)
+			}
+		})
+	}
+}
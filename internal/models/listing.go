@@ -1,57 +1,223 @@
 package models
 
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PropertyTypeCondo  = "condo"
+	PropertyTypeLanded = "landed"
+	PropertyTypeHDB    = "hdb"
+)
+
 type Listing struct {
 	ID          int    `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserID      int    `gorm:"not null;index" json:"user_id" binding:"required"`
 	Price       int    `gorm:"not null" json:"price" binding:"required,min=1"`
 	ListingType string `gorm:"not null" json:"listing_type" binding:"required,oneof=rent sale"`
+	ListingAttributes
+	Status          string     `gorm:"not null;default:active;index" json:"status"`
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+	PreviousPrice   *int       `json:"previous_price"`
+	PriceChangedAt  *time.Time `json:"price_changed_at"`
 	Timestamp
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// ListingAttributes are the optional details describing the property. A nil
+// field is unset, so the same struct serves create requests and partial updates.
+type ListingAttributes struct {
+	Title        *string  `gorm:"size:200" json:"title,omitempty" form:"title" binding:"omitempty,min=1,max=200"`
+	Description  *string  `json:"description,omitempty" form:"description" binding:"omitempty,max=5000"`
+	Address      *string  `gorm:"size:255" json:"address,omitempty" form:"address" binding:"omitempty,max=255"`
+	City         *string  `gorm:"size:100" json:"city,omitempty" form:"city" binding:"omitempty,max=100"`
+	PostalCode   *string  `gorm:"size:6" json:"postal_code,omitempty" form:"postal_code" binding:"omitempty,numeric,len=6"`
+	Bedrooms     *int     `json:"bedrooms,omitempty" form:"bedrooms" binding:"omitempty,min=0,max=50"`
+	Bathrooms    *int     `json:"bathrooms,omitempty" form:"bathrooms" binding:"omitempty,min=0,max=50"`
+	FloorArea    *float64 `json:"floor_area,omitempty" form:"floor_area" binding:"omitempty,gt=0"`
+	PropertyType *string  `json:"property_type,omitempty" form:"property_type" binding:"omitempty,oneof=condo landed hdb"`
+}
+
+// Apply copies every field that is set in update, leaving the rest untouched.
+func (a *ListingAttributes) Apply(update ListingAttributes) {
+	if update.Title != nil {
+		a.Title = update.Title
+	}
+	if update.Description != nil {
+		a.Description = update.Description
+	}
+	if update.Address != nil {
+		a.Address = update.Address
+	}
+	if update.City != nil {
+		a.City = update.City
+	}
+	if update.PostalCode != nil {
+		a.PostalCode = update.PostalCode
+	}
+	if update.Bedrooms != nil {
+		a.Bedrooms = update.Bedrooms
+	}
+	if update.Bathrooms != nil {
+		a.Bathrooms = update.Bathrooms
+	}
+	if update.FloorArea != nil {
+		a.FloorArea = update.FloorArea
+	}
+	if update.PropertyType != nil {
+		a.PropertyType = update.PropertyType
+	}
+}
+
 type ListingResponse struct {
 	ID          int    `json:"id"`
 	UserID      int    `json:"user_id"`
 	ListingType string `json:"listing_type"`
 	Price       int    `json:"price"`
-	CreatedAt   int64  `json:"created_at"`
-	UpdatedAt   int64  `json:"updated_at"`
+	ListingAttributes
+	Status          string `json:"status"`
+	StatusChangedAt *int64 `json:"status_changed_at,omitempty"`
+	PreviousPrice   *int   `json:"previous_price,omitempty"`
+	PriceChangedAt  *int64 `json:"price_changed_at,omitempty"`
+	CreatedAt       int64  `json:"created_at"`
+	UpdatedAt       int64  `json:"updated_at"`
+	DeletedAt       *int64 `json:"deleted_at,omitempty"`
+}
+
+type ListingListResponse struct {
+	Listings []ListingResponse `json:"listings"`
+	PaginationResponse
 }
 
 func (l *Listing) ToResponse() ListingResponse {
-	return ListingResponse{
-		ID:          l.ID,
-		UserID:      l.UserID,
-		ListingType: l.ListingType,
-		Price:       l.Price,
-		CreatedAt:   ToMicroseconds(l.CreatedAt),
-		UpdatedAt:   ToMicroseconds(l.UpdatedAt),
+	response := ListingResponse{
+		ID:                l.ID,
+		UserID:            l.UserID,
+		ListingType:       l.ListingType,
+		Price:             l.Price,
+		ListingAttributes: l.ListingAttributes,
+		Status:            l.Status,
+		PreviousPrice:     l.PreviousPrice,
+		CreatedAt:         ToMicroseconds(l.CreatedAt),
+		UpdatedAt:         ToMicroseconds(l.UpdatedAt),
+	}
+	if l.StatusChangedAt != nil {
+		statusChangedAt := ToMicroseconds(*l.StatusChangedAt)
+		response.StatusChangedAt = &statusChangedAt
+	}
+	if l.PriceChangedAt != nil {
+		priceChangedAt := ToMicroseconds(*l.PriceChangedAt)
+		response.PriceChangedAt = &priceChangedAt
 	}
+	if l.DeletedAt.Valid {
+		deletedAt := ToMicroseconds(l.DeletedAt.Time)
+		response.DeletedAt = &deletedAt
+	}
+	return response
 }
 
 type CreateListingRequest struct {
 	UserID      int    `json:"user_id" form:"user_id" binding:"required"`
 	ListingType string `json:"listing_type" form:"listing_type" binding:"required,oneof=rent sale"`
 	Price       int    `json:"price" form:"price" binding:"required,min=1"`
+	Status      string `json:"status,omitempty" form:"status" binding:"omitempty,oneof=draft active"`
+	ListingAttributes
+}
+type UpdateListingRequest struct {
+	Price *int `json:"price,omitempty" form:"price" binding:"omitempty,min=1"`
+	ListingAttributes
 }
 type GetListingsRequest struct {
 	PaginationRequest
 	UserID *int `form:"user_id" json:"user_id,omitempty"`
+	ListingFilterRequest
+}
+type ListingFilterRequest struct {
+	ListingType string `form:"listing_type" json:"listing_type,omitempty" binding:"omitempty,oneof=rent sale"`
+	PriceMin    *int   `form:"price_min" json:"price_min,omitempty" binding:"omitempty,min=0"`
+	PriceMax    *int   `form:"price_max" json:"price_max,omitempty" binding:"omitempty,min=0"`
+	CreatedFrom *int64 `form:"created_from" json:"created_from,omitempty" binding:"omitempty,min=0"`
+	CreatedTo   *int64 `form:"created_to" json:"created_to,omitempty" binding:"omitempty,min=0"`
+	SortBy      string `form:"sort_by" json:"sort_by,omitempty" binding:"omitempty,oneof=created_at price"`
+	SortOrder   string `form:"sort_order" json:"sort_order,omitempty" binding:"omitempty,oneof=asc desc"`
+	Status      string `form:"status" json:"status,omitempty" binding:"omitempty,oneof=draft active under_offer sold rented expired"`
 }
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByPrice     = "price"
+	SortOrderAsc    = "asc"
+	SortOrderDesc   = "desc"
+)
+
+func (f *ListingFilterRequest) Validate() error {
+	if f.PriceMin != nil && f.PriceMax != nil && *f.PriceMin > *f.PriceMax {
+		return errors.New("price_min must not be greater than price_max")
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && *f.CreatedFrom > *f.CreatedTo {
+		return errors.New("created_from must not be after created_to")
+	}
+	return nil
+}
+func (f *ListingFilterRequest) GetSortBy() string {
+	if f.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return f.SortBy
+}
+func (f *ListingFilterRequest) GetSortOrder() string {
+	if f.SortOrder == "" {
+		return SortOrderDesc
+	}
+	return f.SortOrder
+}
+func (f *ListingFilterRequest) SortKey() string {
+	return f.GetSortBy() + "_" + f.GetSortOrder()
+}
+
 type PublicListingResponse struct {
-	ID          int          `json:"id"`
-	ListingType string       `json:"listing_type"`
-	Price       int          `json:"price"`
-	CreatedAt   int64        `json:"created_at"`
-	UpdatedAt   int64        `json:"updated_at"`
-	User        UserResponse `json:"user"`
+	ID          int    `json:"id"`
+	ListingType string `json:"listing_type"`
+	Price       int    `json:"price"`
+	ListingAttributes
+	Status         string        `json:"status"`
+	PreviousPrice  *int          `json:"previous_price,omitempty"`
+	PriceChangedAt *int64        `json:"price_changed_at,omitempty"`
+	CreatedAt      int64         `json:"created_at"`
+	UpdatedAt      int64         `json:"updated_at"`
+	User           *UserResponse `json:"user"`
+}
+
+type PublicListingListResponse struct {
+	Listings []PublicListingResponse `json:"listings"`
+	PaginationResponse
+	Warnings []PublicListingWarning `json:"warnings,omitempty"`
+}
+
+// PublicListingWarning explains why a listing in a page is missing its owner
+// or was left out of the page.
+type PublicListingWarning struct {
+	ListingID int    `json:"listing_id"`
+	UserID    int    `json:"user_id"`
+	Message   string `json:"message"`
 }
 
 func (l *Listing) ToPublicResponse(user User) PublicListingResponse {
+	response := l.ToResponse()
+	userResponse := user.ToResponse()
 	return PublicListingResponse{
-		ID:          l.ID,
-		ListingType: l.ListingType,
-		Price:       l.Price,
-		CreatedAt:   ToMicroseconds(l.CreatedAt),
-		UpdatedAt:   ToMicroseconds(l.UpdatedAt),
-		User:        user.ToResponse(),
+		ID:                response.ID,
+		ListingType:       response.ListingType,
+		Price:             response.Price,
+		ListingAttributes: response.ListingAttributes,
+		Status:            response.Status,
+		PreviousPrice:     response.PreviousPrice,
+		PriceChangedAt:    response.PriceChangedAt,
+		CreatedAt:         response.CreatedAt,
+		UpdatedAt:         response.UpdatedAt,
+		User:              &userResponse,
 	}
 }
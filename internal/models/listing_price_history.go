@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type ListingPriceChange struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ListingID int       `gorm:"not null;index" json:"listing_id"`
+	OldPrice  int       `gorm:"not null" json:"old_price"`
+	NewPrice  int       `gorm:"not null" json:"new_price"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+type ListingPriceChangeResponse struct {
+	ID        int   `json:"id"`
+	ListingID int   `json:"listing_id"`
+	OldPrice  int   `json:"old_price"`
+	NewPrice  int   `json:"new_price"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+func (ListingPriceChange) TableName() string {
+	return "listing_price_history"
+}
+func (p *ListingPriceChange) ToResponse() ListingPriceChangeResponse {
+	return ListingPriceChangeResponse{
+		ID:        p.ID,
+		ListingID: p.ListingID,
+		OldPrice:  p.OldPrice,
+		NewPrice:  p.NewPrice,
+		CreatedAt: ToMicroseconds(p.CreatedAt),
+	}
+}
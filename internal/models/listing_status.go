@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+const (
+	ListingStatusDraft      = "draft"
+	ListingStatusActive     = "active"
+	ListingStatusUnderOffer = "under_offer"
+	ListingStatusSold       = "sold"
+	ListingStatusRented     = "rented"
+	ListingStatusExpired    = "expired"
+)
+
+var listingStatusTransitions = map[string][]string{
+	ListingStatusDraft:      {ListingStatusActive},
+	ListingStatusActive:     {ListingStatusDraft, ListingStatusUnderOffer, ListingStatusExpired},
+	ListingStatusUnderOffer: {ListingStatusActive, ListingStatusSold, ListingStatusRented},
+	ListingStatusExpired:    {ListingStatusActive},
+}
+
+// CanTransitionListingStatus reports whether a listing of the given type may
+// move from one status to another. Sold and rented are terminal, and only
+// reachable by sale and rent listings respectively.
+func CanTransitionListingStatus(listingType, from, to string) bool {
+	if to == ListingStatusSold && listingType != "sale" {
+		return false
+	}
+	if to == ListingStatusRented && listingType != "rent" {
+		return false
+	}
+	for _, allowed := range listingStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type ListingStatusTransition struct {
+	ID         int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ListingID  int       `gorm:"not null;index" json:"listing_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+type ListingStatusTransitionResponse struct {
+	ID         int    `json:"id"`
+	ListingID  int    `json:"listing_id"`
+	FromStatus string `json:"from_status,omitempty"`
+	ToStatus   string `json:"to_status"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+func (t *ListingStatusTransition) ToResponse() ListingStatusTransitionResponse {
+	return ListingStatusTransitionResponse{
+		ID:         t.ID,
+		ListingID:  t.ListingID,
+		FromStatus: t.FromStatus,
+		ToStatus:   t.ToStatus,
+		CreatedAt:  ToMicroseconds(t.CreatedAt),
+	}
+}
+
+type TransitionListingRequest struct {
+	Status string `json:"status" form:"status" binding:"required,oneof=draft active under_offer sold rented expired"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestCanTransitionListingStatus(t *testing.T) {
+	statuses := []string{
+		ListingStatusDraft,
+		ListingStatusActive,
+		ListingStatusUnderOffer,
+		ListingStatusSold,
+		ListingStatusRented,
+		ListingStatusExpired,
+	}
+	// allowed lists every permitted transition; any other pair is forbidden.
+	allowed := map[string]map[[2]string]bool{
+		"sale": {
+			{ListingStatusDraft, ListingStatusActive}:      true,
+			{ListingStatusActive, ListingStatusDraft}:      true,
+			{ListingStatusActive, ListingStatusUnderOffer}: true,
+			{ListingStatusActive, ListingStatusExpired}:    true,
+			{ListingStatusUnderOffer, ListingStatusActive}: true,
+			{ListingStatusUnderOffer, ListingStatusSold}:   true,
+			{ListingStatusExpired, ListingStatusActive}:    true,
+		},
+		"rent": {
+			{ListingStatusDraft, ListingStatusActive}:      true,
+			{ListingStatusActive, ListingStatusDraft}:      true,
+			{ListingStatusActive, ListingStatusUnderOffer}: true,
+			{ListingStatusActive, ListingStatusExpired}:    true,
+			{ListingStatusUnderOffer, ListingStatusActive}: true,
+			{ListingStatusUnderOffer, ListingStatusRented}: true,
+			{ListingStatusExpired, ListingStatusActive}:    true,
+		},
+	}
+	for listingType, transitions := range allowed {
+		for _, from := range statuses {
+			for _, to := range statuses {
+				want := transitions[[2]string{from, to}]
+				if got := CanTransitionListingStatus(listingType, from, to); got != want {
+					t.Errorf("CanTransitionListingStatus(%q, %q, %q) = %v, want %v", listingType, from, to, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCanTransitionListingStatusRejectsUnknownStatuses(t *testing.T) {
+	tests := []struct {
+		listingType, from, to string
+	}{
+		{"sale", "", ListingStatusActive},
+		{"sale", "archived", ListingStatusActive},
+		{"sale", ListingStatusActive, "archived"},
+		{"lease", ListingStatusUnderOffer, ListingStatusSold},
+		{"lease", ListingStatusUnderOffer, ListingStatusRented},
+	}
+	for _, tt := range tests {
+		if CanTransitionListingStatus(tt.listingType, tt.from, tt.to) {
+			t.Errorf("CanTransitionListingStatus(%q, %q, %q) = true, want false", tt.listingType, tt.from, tt.to)
+		}
+	}
+}
@@ -1,29 +1,46 @@
 package models
 
+import "gorm.io/gorm"
+
 type User struct {
 	ID   int    `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name string `gorm:"not null" json:"name" binding:"required"`
 	Timestamp
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 type UserResponse struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	CreatedAt int64  `json:"created_at"`
 	UpdatedAt int64  `json:"updated_at"`
+	DeletedAt *int64 `json:"deleted_at,omitempty"`
 }
 
 func (u *User) ToResponse() UserResponse {
-	return UserResponse{
+	response := UserResponse{
 		ID:        u.ID,
 		Name:      u.Name,
 		CreatedAt: ToMicroseconds(u.CreatedAt),
 		UpdatedAt: ToMicroseconds(u.UpdatedAt),
 	}
+	if u.DeletedAt.Valid {
+		deletedAt := ToMicroseconds(u.DeletedAt.Time)
+		response.DeletedAt = &deletedAt
+	}
+	return response
 }
 
 type CreateUserRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name string `json:"name" form:"name" binding:"required"`
+}
+type UserListResponse struct {
+	Users []UserResponse `json:"users"`
+	PaginationResponse
+}
+type UpdateUserRequest struct {
+	Name *string `json:"name" form:"name" binding:"omitempty,min=1"`
 }
 type GetUsersRequest struct {
 	PaginationRequest
+	IDs string `form:"ids" json:"ids,omitempty"`
 }
@@ -0,0 +1,169 @@
+package publicapi
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+// CircuitBreakerConfig controls when a breaker trips. After FailureThreshold
+// consecutive failures it opens and rejects calls for OpenTimeout, then lets up
+// to HalfOpenMaxCalls trial calls through to decide whether to close again.
+type CircuitBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenMaxCalls int
+}
+type CircuitBreakerStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	Opens               int64      `json:"opens"`
+	Rejected            int64      `json:"rejected"`
+}
+type CircuitBreaker struct {
+	name                string
+	config              CircuitBreakerConfig
+	mu                  sync.Mutex
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	halfOpenCalls       int
+	opens               int64
+	rejected            int64
+	now                 func() time.Time
+}
+func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:   name,
+		config: config,
+		state:  CircuitClosed,
+		now:    time.Now,
+	}
+}
+func (b *CircuitBreaker) Status() CircuitBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	status := CircuitBreakerStatus{
+		Name:                b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		Opens:               b.opens,
+		Rejected:            b.rejected,
+	}
+	if b.state != CircuitClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+// allow reports whether a call may go through, reserving a trial slot when the
+// breaker is half-open.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	switch b.state {
+	case CircuitOpen:
+		b.rejected++
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.halfOpenCalls >= b.config.HalfOpenMaxCalls {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.halfOpenCalls++
+	}
+	return nil
+}
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = CircuitClosed
+		b.consecutiveFailures = 0
+		b.halfOpenCalls = 0
+		return
+	}
+	b.consecutiveFailures++
+	if b.state == CircuitHalfOpen || b.consecutiveFailures >= b.config.FailureThreshold {
+		b.trip()
+	}
+}
+// release gives back a trial slot without counting the call either way.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen && b.halfOpenCalls > 0 {
+		b.halfOpenCalls--
+	}
+}
+// refresh moves an open breaker to half-open once its timeout has elapsed.
+func (b *CircuitBreaker) refresh() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = CircuitHalfOpen
+		b.halfOpenCalls = 0
+	}
+}
+func (b *CircuitBreaker) trip() {
+	if b.state != CircuitOpen {
+		b.opens++
+	}
+	b.state = CircuitOpen
+	b.openedAt = b.now()
+	b.halfOpenCalls = 0
+}
+type breakerHTTPClient struct {
+	next    HTTPClient
+	breaker *CircuitBreaker
+}
+// NewCircuitBreakerHTTPClient guards next with breaker. Network errors, timeouts
+// and 5xx responses count as failures.
+func NewCircuitBreakerHTTPClient(next HTTPClient, breaker *CircuitBreaker) HTTPClient {
+	return &breakerHTTPClient{
+		next:    next,
+		breaker: breaker,
+	}
+}
+func (c *breakerHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, func() (*http.Response, error) {
+		return c.next.Get(ctx, url)
+	})
+}
+func (c *breakerHTTPClient) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, func() (*http.Response, error) {
+		return c.next.PostForm(ctx, url, data)
+	})
+}
+func (c *breakerHTTPClient) PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, func() (*http.Response, error) {
+		return c.next.PatchForm(ctx, url, data)
+	})
+}
+func (c *breakerHTTPClient) Delete(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, func() (*http.Response, error) {
+		return c.next.Delete(ctx, url)
+	})
+}
+func (c *breakerHTTPClient) do(ctx context.Context, call func() (*http.Response, error)) (*http.Response, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := call()
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		// The caller gave up; that says nothing about the upstream's health.
+		c.breaker.release()
+		return nil, err
+	}
+	c.breaker.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
@@ -0,0 +1,169 @@
+package publicapi
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+func newTestCircuitBreaker(config CircuitBreakerConfig) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	breaker := NewCircuitBreaker(userServiceName, config)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+// breakerStep advances the clock, then makes one call through the breaker that
+// the upstream answers with result unless the breaker rejects it.
+type breakerStep struct {
+	advance      time.Duration
+	result       stubResult
+	wantRejected bool
+	wantState    string
+}
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	config := CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: 30 * time.Second, HalfOpenMaxCalls: 1}
+	ok := stubResult{status: http.StatusOK}
+	notFound := stubResult{status: http.StatusNotFound}
+	serverError := stubResult{status: http.StatusInternalServerError}
+	networkError := stubResult{err: errConnectionRefused}
+	timeout := stubResult{err: context.DeadlineExceeded}
+	tests := []struct {
+		name      string
+		steps     []breakerStep
+		wantOpens int64
+	}{
+		{
+			name: "opens at the failure threshold",
+			steps: []breakerStep{
+				{result: serverError, wantState: CircuitClosed},
+				{result: networkError, wantState: CircuitClosed},
+				{result: timeout, wantState: CircuitOpen},
+				{result: ok, wantRejected: true, wantState: CircuitOpen},
+			},
+			wantOpens: 1,
+		},
+		{
+			name: "success resets the failure count",
+			steps: []breakerStep{
+				{result: serverError, wantState: CircuitClosed},
+				{result: serverError, wantState: CircuitClosed},
+				{result: ok, wantState: CircuitClosed},
+				{result: serverError, wantState: CircuitClosed},
+				{result: serverError, wantState: CircuitClosed},
+			},
+		},
+		{
+			name: "client errors are not failures",
+			steps: []breakerStep{
+				{result: notFound, wantState: CircuitClosed},
+				{result: notFound, wantState: CircuitClosed},
+				{result: notFound, wantState: CircuitClosed},
+				{result: notFound, wantState: CircuitClosed},
+			},
+		},
+		{
+			name: "half-open after the open timeout and closes on a successful probe",
+			steps: []breakerStep{
+				{result: serverError},
+				{result: serverError},
+				{result: serverError, wantState: CircuitOpen},
+				{advance: 29 * time.Second, result: ok, wantRejected: true, wantState: CircuitOpen},
+				{advance: time.Second, result: ok, wantState: CircuitClosed},
+				{result: serverError, wantState: CircuitClosed},
+			},
+			wantOpens: 1,
+		},
+		{
+			name: "a failed probe reopens the breaker for another full timeout",
+			steps: []breakerStep{
+				{result: serverError},
+				{result: serverError},
+				{result: serverError, wantState: CircuitOpen},
+				{advance: 30 * time.Second, result: networkError, wantState: CircuitOpen},
+				{advance: 29 * time.Second, result: ok, wantRejected: true, wantState: CircuitOpen},
+				{advance: time.Second, result: ok, wantState: CircuitClosed},
+			},
+			wantOpens: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker, clock := newTestCircuitBreaker(config)
+			var rejected int64
+			for i, step := range tt.steps {
+				clock.Advance(step.advance)
+				stub := newStubHTTPClient(step.result)
+				client := NewCircuitBreakerHTTPClient(stub, breaker)
+				_, err := client.Get(context.Background(), "http://user-service/users/1")
+				if gotRejected := errors.Is(err, ErrCircuitOpen); gotRejected != step.wantRejected {
+					t.Fatalf("step %d: rejected = %v (err %v), want %v", i, gotRejected, err, step.wantRejected)
+				}
+				if step.wantRejected {
+					rejected++
+					if stub.Calls() != 0 {
+						t.Fatalf("step %d: a rejected call reached the upstream", i)
+					}
+				}
+				if state := breaker.Status().State; step.wantState != "" && state != step.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, state, step.wantState)
+				}
+			}
+			status := breaker.Status()
+			if status.Opens != tt.wantOpens || status.Rejected != rejected {
+				t.Errorf("status = %+v, want %d opens and %d rejected", status, tt.wantOpens, rejected)
+			}
+		})
+	}
+}
+func TestCircuitBreakerHalfOpenProbeLimit(t *testing.T) {
+	tests := []struct {
+		name             string
+		halfOpenMaxCalls int
+	}{
+		{name: "one probe", halfOpenMaxCalls: 1},
+		{name: "three probes", halfOpenMaxCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker, clock := newTestCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxCalls: tt.halfOpenMaxCalls})
+			breaker.record(false)
+			clock.Advance(time.Minute)
+			for i := 0; i < tt.halfOpenMaxCalls; i++ {
+				if err := breaker.allow(); err != nil {
+					t.Fatalf("probe %d: allow() = %v, want nil", i, err)
+				}
+			}
+			if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("allow() with all probes in flight = %v, want ErrCircuitOpen", err)
+			}
+			breaker.release()
+			if err := breaker.allow(); err != nil {
+				t.Fatalf("allow() after a probe was released = %v, want nil", err)
+			}
+			if state := breaker.Status().State; state != CircuitHalfOpen {
+				t.Fatalf("state = %s, want %s", state, CircuitHalfOpen)
+			}
+			breaker.record(true)
+			if err := breaker.allow(); err != nil {
+				t.Fatalf("allow() after a successful probe = %v, want nil", err)
+			}
+		})
+	}
+}
+func TestCircuitBreakerIgnoresCallerCancellation(t *testing.T) {
+	breaker, clock := newTestCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxCalls: 1})
+	breaker.record(false)
+	clock.Advance(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewCircuitBreakerHTTPClient(newStubHTTPClient(stubResult{err: context.Canceled}), breaker)
+	if _, err := client.Get(ctx, "http://user-service/users/1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get() = %v, want context.Canceled", err)
+	}
+	if state := breaker.Status().State; state != CircuitHalfOpen {
+		t.Fatalf("state = %s, want %s: a canceled probe must not count", state, CircuitHalfOpen)
+	}
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() = %v, want the canceled probe's slot to be free again", err)
+	}
+}
@@ -0,0 +1,135 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+// UserCache stores user lookups. An entry with a nil User records that the user
+// does not exist (negative caching). Implementations must be safe for
+// concurrent use; the context is there for remote backends.
+type UserCache interface {
+	Get(ctx context.Context, userID int) (entry UserCacheEntry, ok bool)
+	Set(ctx context.Context, userID int, entry UserCacheEntry)
+	Delete(ctx context.Context, userID int)
+	Stats() CacheStats
+}
+type UserCacheEntry struct {
+	User *models.UserResponse
+}
+type UserCacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+type CacheStats struct {
+	Enabled      bool  `json:"enabled"`
+	Size         int   `json:"size"`
+	Capacity     int   `json:"capacity"`
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negative_hits"`
+	Misses       int64 `json:"misses"`
+	Evictions    int64 `json:"evictions"`
+	Expirations  int64 `json:"expirations"`
+}
+// NewUserCache returns an in-process LRU cache with per-entry expiry, or a
+// cache that stores nothing when config.Size is not positive.
+func NewUserCache(config UserCacheConfig) UserCache {
+	if config.Size <= 0 {
+		return noopUserCache{}
+	}
+	return &lruUserCache{
+		config:  config,
+		entries: make(map[int]*list.Element, config.Size),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+type lruUserCache struct {
+	config  UserCacheConfig
+	mu      sync.Mutex
+	entries map[int]*list.Element
+	order   *list.List
+	stats   CacheStats
+	now     func() time.Time
+}
+type lruItem struct {
+	userID    int
+	entry     UserCacheEntry
+	expiresAt time.Time
+}
+func (c *lruUserCache) Get(ctx context.Context, userID int) (UserCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[userID]
+	if !ok {
+		c.stats.Misses++
+		return UserCacheEntry{}, false
+	}
+	item := element.Value.(*lruItem)
+	if c.now().After(item.expiresAt) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return UserCacheEntry{}, false
+	}
+	c.order.MoveToFront(element)
+	if item.entry.User == nil {
+		c.stats.NegativeHits++
+	} else {
+		c.stats.Hits++
+	}
+	return item.entry, true
+}
+func (c *lruUserCache) Set(ctx context.Context, userID int, entry UserCacheEntry) {
+	ttl := c.config.TTL
+	if entry.User == nil {
+		ttl = c.config.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item := &lruItem{userID: userID, entry: entry, expiresAt: c.now().Add(ttl)}
+	if element, ok := c.entries[userID]; ok {
+		element.Value = item
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[userID] = c.order.PushFront(item)
+	for c.order.Len() > c.config.Size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+func (c *lruUserCache) Delete(ctx context.Context, userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[userID]; ok {
+		c.remove(element)
+	}
+}
+func (c *lruUserCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Enabled = true
+	stats.Size = c.order.Len()
+	stats.Capacity = c.config.Size
+	return stats
+}
+func (c *lruUserCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruItem).userID)
+}
+type noopUserCache struct{}
+func (noopUserCache) Get(ctx context.Context, userID int) (UserCacheEntry, bool) {
+	return UserCacheEntry{}, false
+}
+func (noopUserCache) Set(ctx context.Context, userID int, entry UserCacheEntry) {}
+func (noopUserCache) Delete(ctx context.Context, userID int) {}
+func (noopUserCache) Stats() CacheStats {
+	return CacheStats{}
+}
@@ -0,0 +1,242 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+func newTestUserCache(config UserCacheConfig) (*lruUserCache, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cache := NewUserCache(config).(*lruUserCache)
+	cache.now = clock.Now
+	return cache, clock
+}
+func cachedUser(id int) UserCacheEntry {
+	return UserCacheEntry{User: &models.UserResponse{ID: id, Name: "user " + strconv.Itoa(id)}}
+}
+func TestUserCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestUserCache(UserCacheConfig{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute})
+	cache.Set(ctx, 1, cachedUser(1))
+	cache.Set(ctx, 2, cachedUser(2))
+	if _, ok := cache.Get(ctx, 1); !ok {
+		t.Fatal("user 1 should be cached")
+	}
+	cache.Set(ctx, 3, cachedUser(3))
+	if _, ok := cache.Get(ctx, 2); ok {
+		t.Error("user 2 was least recently used and should have been evicted")
+	}
+	for _, id := range []int{1, 3} {
+		if entry, ok := cache.Get(ctx, id); !ok || entry.User.ID != id {
+			t.Errorf("user %d should still be cached, got %+v, %v", id, entry, ok)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Size != 2 || stats.Capacity != 2 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want size 2, capacity 2, 1 eviction", stats)
+	}
+}
+func TestUserCacheSetReplacesExistingEntry(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestUserCache(UserCacheConfig{Size: 2, TTL: time.Minute, NegativeTTL: time.Minute})
+	cache.Set(ctx, 1, UserCacheEntry{})
+	cache.Set(ctx, 1, cachedUser(1))
+	entry, ok := cache.Get(ctx, 1)
+	if !ok || entry.User == nil {
+		t.Fatalf("Get(1) = %+v, %v, want the user", entry, ok)
+	}
+	if stats := cache.Stats(); stats.Size != 1 || stats.Evictions != 0 {
+		t.Errorf("stats = %+v, want one entry and no evictions", stats)
+	}
+}
+func TestUserCacheExpiresEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry UserCacheEntry
+	}{
+		{name: "user", entry: cachedUser(1)},
+		{name: "not found", entry: UserCacheEntry{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache, clock := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: 10 * time.Second})
+			ttl := cache.config.TTL
+			if tt.entry.User == nil {
+				ttl = cache.config.NegativeTTL
+			}
+			cache.Set(ctx, 1, tt.entry)
+			clock.Advance(ttl)
+			if _, ok := cache.Get(ctx, 1); !ok {
+				t.Fatal("entry should still be cached at exactly its TTL")
+			}
+			clock.Advance(time.Nanosecond)
+			if _, ok := cache.Get(ctx, 1); ok {
+				t.Fatal("entry should have expired")
+			}
+			stats := cache.Stats()
+			if stats.Size != 0 || stats.Expirations != 1 {
+				t.Errorf("stats = %+v, want an empty cache and 1 expiration", stats)
+			}
+		})
+	}
+}
+func TestUserCacheNegativeEntries(t *testing.T) {
+	ctx := context.Background()
+	cache, clock := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: 10 * time.Second})
+	cache.Set(ctx, 1, UserCacheEntry{})
+	entry, ok := cache.Get(ctx, 1)
+	if !ok || entry.User != nil {
+		t.Fatalf("Get(1) = %+v, %v, want a negative entry", entry, ok)
+	}
+	clock.Advance(11 * time.Second)
+	if _, ok := cache.Get(ctx, 1); ok {
+		t.Error("negative entry should expire after NegativeTTL, not TTL")
+	}
+	stats := cache.Stats()
+	if stats.NegativeHits != 1 || stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 1 negative hit, 0 hits, 1 miss", stats)
+	}
+}
+func TestUserCacheSkipsEntriesWithoutTTL(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute})
+	cache.Set(ctx, 1, UserCacheEntry{})
+	if _, ok := cache.Get(ctx, 1); ok {
+		t.Error("negative entries should not be cached when NegativeTTL is 0")
+	}
+}
+func TestUserCacheDelete(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+	cache.Set(ctx, 1, cachedUser(1))
+	cache.Delete(ctx, 1)
+	cache.Delete(ctx, 2)
+	if _, ok := cache.Get(ctx, 1); ok {
+		t.Error("deleted user should not be cached")
+	}
+}
+func TestNewUserCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	cache := NewUserCache(UserCacheConfig{Size: 0, TTL: time.Minute})
+	cache.Set(ctx, 1, cachedUser(1))
+	if _, ok := cache.Get(ctx, 1); ok {
+		t.Error("a cache of size 0 should store nothing")
+	}
+	if cache.Stats().Enabled {
+		t.Error("a cache of size 0 should report itself as disabled")
+	}
+}
+// newTestService returns a service whose user service is handler. Retries are
+// off so each lookup reaches handler exactly once.
+func newTestService(t *testing.T, handler http.HandlerFunc, cache UserCache) *service {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	config := NewConfig()
+	config.UserService.URL = server.URL
+	config.UserService.Retry.MaxAttempts = 1
+	config.UserLookupMode = UserLookupFanOut
+	return NewService(NewServiceClient(config), cache, config).(*service)
+}
+func writeEnvelope(w http.ResponseWriter, status int, response models.Response) {
+	response.Version = models.ResponseVersion
+	response.Result = status < http.StatusBadRequest
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+func TestServiceCachesOnlyConfirmedMissingUsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		errorCode  string
+		wantCached bool
+	}{
+		{name: "user not found", status: http.StatusNotFound, errorCode: models.ErrorCodeUserNotFound, wantCached: true},
+		{name: "404 without error code", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusInternalServerError},
+		{name: "unavailable", status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache, _ := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+			s := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				writeEnvelope(w, tt.status, models.Response{Message: "Failed to get user", ErrorCode: tt.errorCode})
+			}, cache)
+			users, lookupErrs := s.getUsers(ctx, []int{7})
+			if len(users) != 0 || lookupErrs[7] == nil {
+				t.Fatalf("getUsers = %v, %v, want a lookup error for user 7", users, lookupErrs)
+			}
+			entry, cached := cache.Get(ctx, 7)
+			if cached != tt.wantCached {
+				t.Fatalf("cached = %v, want %v", cached, tt.wantCached)
+			}
+			if cached && entry.User != nil {
+				t.Errorf("cached entry = %+v, want a negative entry", entry)
+			}
+		})
+	}
+}
+func TestServiceInvalidatesCachedUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		call   func(s Service, ctx context.Context) error
+	}{
+		{name: "update", status: http.StatusOK, call: func(s Service, ctx context.Context) error {
+			name := "renamed"
+			_, err := s.UpdateUser(ctx, 1, &name)
+			return err
+		}},
+		{name: "delete", status: http.StatusOK, call: func(s Service, ctx context.Context) error {
+			_, err := s.DeleteUser(ctx, 1)
+			return err
+		}},
+		{name: "failed update", status: http.StatusInternalServerError, call: func(s Service, ctx context.Context) error {
+			name := "renamed"
+			_, err := s.UpdateUser(ctx, 1, &name)
+			return err
+		}},
+		{name: "failed delete", status: http.StatusInternalServerError, call: func(s Service, ctx context.Context) error {
+			_, err := s.DeleteUser(ctx, 1)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var lookups atomic.Int32
+			cache, _ := newTestUserCache(UserCacheConfig{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+			s := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					lookups.Add(1)
+				} else if tt.status != http.StatusOK {
+					writeEnvelope(w, tt.status, models.Response{Message: "Failed"})
+					return
+				}
+				id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/users/"))
+				writeEnvelope(w, http.StatusOK, models.Response{Data: map[string]interface{}{"user": models.UserResponse{ID: id, Name: "fresh"}}})
+			}, cache)
+			cache.Set(ctx, 1, cachedUser(1))
+			err := tt.call(s, ctx)
+			if (err != nil) != (tt.status != http.StatusOK) {
+				t.Fatalf("call error = %v, want failure %v", err, tt.status != http.StatusOK)
+			}
+			if _, ok := cache.Get(ctx, 1); ok {
+				t.Fatal("user 1 should have been dropped from the cache")
+			}
+			users, _ := s.getUsers(ctx, []int{1})
+			if users[1].Name != "fresh" || lookups.Load() != 1 {
+				t.Errorf("getUsers = %v after %d lookups, want the fresh user from one lookup", users, lookups.Load())
+			}
+		})
+	}
+}
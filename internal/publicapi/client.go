@@ -1,16 +1,23 @@
 package publicapi
 import (
-	"encoding/json"
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/logging"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
-	"time"
+	"strings"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
+// HTTPClient performs requests against the backend services. Every call is
+// bound to ctx, so cancelling it aborts the request in flight.
 type HTTPClient interface {
-	Get(url string) (*http.Response, error)
-	PostForm(url string, data url.Values) (*http.Response, error)
+	Get(ctx context.Context, url string) (*http.Response, error)
+	PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error)
+	PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error)
+	Delete(ctx context.Context, url string) (*http.Response, error)
 }
 type DefaultHTTPClient struct {
 	client *http.Client
@@ -18,150 +25,332 @@ type DefaultHTTPClient struct {
 func NewHTTPClient() HTTPClient {
 	return &DefaultHTTPClient{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 }
-func (c *DefaultHTTPClient) Get(url string) (*http.Response, error) {
-	return c.client.Get(url)
+func (c *DefaultHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, url, nil)
 }
-func (c *DefaultHTTPClient) PostForm(url string, data url.Values) (*http.Response, error) {
-	return c.client.PostForm(url, data)
+func (c *DefaultHTTPClient) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, url, data)
 }
+func (c *DefaultHTTPClient) PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, http.MethodPatch, url, data)
+}
+func (c *DefaultHTTPClient) Delete(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, http.MethodDelete, url, nil)
+}
+func (c *DefaultHTTPClient) do(ctx context.Context, method, url string, data url.Values) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = strings.NewReader(data.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	// Reads made while serving a write, such as the owner check before a
+	// listing is created, must not claim the write's key upstream.
+	if key := idempotencyKey(ctx); key != "" && method != http.MethodGet {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if id := logging.RequestID(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	return c.client.Do(req)
+}
+// Operation names identify each ServiceClient call, e.g. for per-call timeouts.
+const (
+	OpGetUser       = "get_user"
+	OpGetUsers      = "get_users"
+	OpCreateUser    = "create_user"
+	OpUpdateUser    = "update_user"
+	OpDeleteUser    = "delete_user"
+	OpGetListings   = "get_listings"
+	OpGetListing    = "get_listing"
+	OpCreateListing = "create_listing"
+	OpUpdateListing = "update_listing"
+	OpDeleteListing = "delete_listing"
+)
 type ServiceClient struct {
-	httpClient        HTTPClient
+	userClient        HTTPClient
+	listingClient     HTTPClient
+	userBreaker       *CircuitBreaker
+	listingBreaker    *CircuitBreaker
 	userServiceURL    string
 	listingServiceURL string
+	timeouts          TimeoutConfig
 }
-func NewServiceClient(userServiceURL, listingServiceURL string) *ServiceClient {
+func NewServiceClient(config *Config) *ServiceClient {
+	userBreaker := NewCircuitBreaker(userServiceName, config.UserService.Breaker)
+	listingBreaker := NewCircuitBreaker(listingServiceName, config.ListingService.Breaker)
 	return &ServiceClient{
-		httpClient:        NewHTTPClient(),
-		userServiceURL:    userServiceURL,
-		listingServiceURL: listingServiceURL,
+		userClient:        newUpstreamHTTPClient(config.UserService, userBreaker),
+		listingClient:     newUpstreamHTTPClient(config.ListingService, listingBreaker),
+		userBreaker:       userBreaker,
+		listingBreaker:    listingBreaker,
+		userServiceURL:    config.UserService.URL,
+		listingServiceURL: config.ListingService.URL,
+		timeouts:          config.Timeouts,
 	}
 }
-type UserServiceResponse struct {
-	Result bool        `json:"result"`
-	Data   interface{} `json:"data"`
-	Error  interface{} `json:"error"`
+// newUpstreamHTTPClient puts the breaker in front of the retries, so an open
+// circuit fails fast instead of backing off. Metrics see each call once,
+// including the ones the breaker rejects.
+func newUpstreamHTTPClient(config UpstreamConfig, breaker *CircuitBreaker) HTTPClient {
+	client := NewRetryingHTTPClient(NewHTTPClient(), config.Retry)
+	client = NewCircuitBreakerHTTPClient(client, breaker)
+	return NewInstrumentedHTTPClient(client, upstreamLabel(breaker.name))
+}
+func (sc *ServiceClient) CircuitBreakers() []CircuitBreakerStatus {
+	return []CircuitBreakerStatus{sc.userBreaker.Status(), sc.listingBreaker.Status()}
 }
-type ListingServiceResponse struct {
-	Result bool        `json:"result"`
-	Data   interface{} `json:"data"`
-	Error  interface{} `json:"error"`
+// withTimeout bounds ctx by the timeout configured for op and tags it with op
+// for metrics.
+func (sc *ServiceClient) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(withOperation(ctx, op), sc.timeouts.For(op))
 }
-func (sc *ServiceClient) GetUser(userID int) (map[string]interface{}, error) {
+func (sc *ServiceClient) GetUser(ctx context.Context, userID int) (*models.UserResponse, error) {
 	url := fmt.Sprintf("%s/users/%d", sc.userServiceURL, userID)
-	resp, err := sc.httpClient.Get(url)
+	ctx, cancel := sc.withTimeout(ctx, OpGetUser)
+	defer cancel()
+	resp, err := sc.userClient.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call user service: %w", err)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	user, err := decodeEnvelope[models.UserResponse](userServiceName, resp, "user")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
-	var response UserServiceResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	return &user, nil
+}
+func (sc *ServiceClient) GetUsersByIDs(ctx context.Context, userIDs []int) ([]models.UserResponse, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
 	}
-	if !response.Result {
-		return nil, fmt.Errorf("user service error: %v", response.Error)
+	ids := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = strconv.Itoa(id)
 	}
-	if data, ok := response.Data.(map[string]interface{}); ok {
-		if user, ok := data["user"].(map[string]interface{}); ok {
-			return user, nil
-		}
+	params := url.Values{
+		"ids": {strings.Join(ids, ",")},
 	}
-	return nil, fmt.Errorf("unexpected response format")
+	url := fmt.Sprintf("%s/users?%s", sc.userServiceURL, params.Encode())
+	ctx, cancel := sc.withTimeout(ctx, OpGetUsers)
+	defer cancel()
+	resp, err := sc.userClient.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call user service: %w", err)
+	}
+	defer resp.Body.Close()
+	return decodeEnvelope[[]models.UserResponse](userServiceName, resp, "users")
 }
-func (sc *ServiceClient) CreateUser(name string) (map[string]interface{}, error) {
+func (sc *ServiceClient) CreateUser(ctx context.Context, name string) (*models.UserResponse, error) {
 	data := url.Values{
 		"name": {name},
 	}
 	url := fmt.Sprintf("%s/users", sc.userServiceURL)
-	resp, err := sc.httpClient.PostForm(url, data)
+	ctx, cancel := sc.withTimeout(ctx, OpCreateUser)
+	defer cancel()
+	resp, err := sc.userClient.PostForm(ctx, url, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call user service: %w", err)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	user, err := decodeEnvelope[models.UserResponse](userServiceName, resp, "user")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
-	var response UserServiceResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	return &user, nil
+}
+func (sc *ServiceClient) UpdateUser(ctx context.Context, userID int, name *string) (*models.UserResponse, error) {
+	data := url.Values{}
+	if name != nil {
+		data.Set("name", *name)
 	}
-	if !response.Result {
-		return nil, fmt.Errorf("user service error: %v", response.Error)
+	url := fmt.Sprintf("%s/users/%d", sc.userServiceURL, userID)
+	ctx, cancel := sc.withTimeout(ctx, OpUpdateUser)
+	defer cancel()
+	resp, err := sc.userClient.PatchForm(ctx, url, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call user service: %w", err)
 	}
-	if data, ok := response.Data.(map[string]interface{}); ok {
-		if user, ok := data["user"].(map[string]interface{}); ok {
-			return user, nil
-		}
+	defer resp.Body.Close()
+	user, err := decodeEnvelope[models.UserResponse](userServiceName, resp, "user")
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("unexpected response format")
+	return &user, nil
 }
-func (sc *ServiceClient) GetListings(pageNum, pageSize int, userID *int) ([]interface{}, error) {
-	params := url.Values{
-		"page_num":  {strconv.Itoa(pageNum)},
-		"page_size": {strconv.Itoa(pageSize)},
+func (sc *ServiceClient) DeleteUser(ctx context.Context, userID int) (*models.UserResponse, error) {
+	url := fmt.Sprintf("%s/users/%d", sc.userServiceURL, userID)
+	ctx, cancel := sc.withTimeout(ctx, OpDeleteUser)
+	defer cancel()
+	resp, err := sc.userClient.Delete(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call user service: %w", err)
 	}
-	if userID != nil {
-		params.Set("user_id", strconv.Itoa(*userID))
+	defer resp.Body.Close()
+	user, err := decodeEnvelope[models.UserResponse](userServiceName, resp, "user")
+	if err != nil {
+		return nil, err
 	}
+	return &user, nil
+}
+func (sc *ServiceClient) GetListings(ctx context.Context, request models.GetListingsRequest) (*models.ListingListResponse, error) {
+	params := url.Values{}
+	if request.IsCursorMode() {
+		params.Set("limit", strconv.Itoa(request.GetLimit()))
+		if request.Cursor != "" {
+			params.Set("cursor", request.Cursor)
+		}
+	} else {
+		params.Set("page_num", strconv.Itoa(request.GetPageNum()))
+		params.Set("page_size", strconv.Itoa(request.GetPageSize()))
+	}
+	if request.UserID != nil {
+		params.Set("user_id", strconv.Itoa(*request.UserID))
+	}
+	addListingFilterParams(params, request.ListingFilterRequest)
 	url := fmt.Sprintf("%s/listings?%s", sc.listingServiceURL, params.Encode())
-	resp, err := sc.httpClient.Get(url)
+	ctx, cancel := sc.withTimeout(ctx, OpGetListings)
+	defer cancel()
+	resp, err := sc.listingClient.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call listing service: %w", err)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	listings, err := decodeEnvelope[models.ListingListResponse](listingServiceName, resp, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
-	var response ListingServiceResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	return &listings, nil
+}
+func (sc *ServiceClient) CreateListing(ctx context.Context, request models.CreateListingRequest) (*models.ListingResponse, error) {
+	data := url.Values{
+		"user_id":      {strconv.Itoa(request.UserID)},
+		"listing_type": {request.ListingType},
+		"price":        {strconv.Itoa(request.Price)},
 	}
-	if !response.Result {
-		return nil, fmt.Errorf("listing service error: %v", response.Error)
+	if request.Status != "" {
+		data.Set("status", request.Status)
 	}
-	if data, ok := response.Data.(map[string]interface{}); ok {
-		if listings, ok := data["listings"].([]interface{}); ok {
-			return listings, nil
-		}
+	addListingAttributeParams(data, request.ListingAttributes)
+	url := fmt.Sprintf("%s/listings", sc.listingServiceURL)
+	ctx, cancel := sc.withTimeout(ctx, OpCreateListing)
+	defer cancel()
+	resp, err := sc.listingClient.PostForm(ctx, url, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call listing service: %w", err)
 	}
-	return nil, fmt.Errorf("unexpected response format")
+	defer resp.Body.Close()
+	listing, err := decodeEnvelope[models.ListingResponse](listingServiceName, resp, "listing")
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
 }
-func (sc *ServiceClient) CreateListing(userID int, listingType string, price int) (map[string]interface{}, error) {
-	data := url.Values{
-		"user_id":      {strconv.Itoa(userID)},
-		"listing_type": {listingType},
-		"price":        {strconv.Itoa(price)},
+func (sc *ServiceClient) GetListing(ctx context.Context, listingID int) (*models.ListingResponse, error) {
+	url := fmt.Sprintf("%s/listings/%d", sc.listingServiceURL, listingID)
+	ctx, cancel := sc.withTimeout(ctx, OpGetListing)
+	defer cancel()
+	resp, err := sc.listingClient.Get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call listing service: %w", err)
 	}
-	url := fmt.Sprintf("%s/listings", sc.listingServiceURL)
-	resp, err := sc.httpClient.PostForm(url, data)
+	defer resp.Body.Close()
+	listing, err := decodeEnvelope[models.ListingResponse](listingServiceName, resp, "listing")
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+func (sc *ServiceClient) UpdateListing(ctx context.Context, listingID int, request models.UpdateListingRequest) (*models.ListingResponse, error) {
+	data := url.Values{}
+	if request.Price != nil {
+		data.Set("price", strconv.Itoa(*request.Price))
+	}
+	addListingAttributeParams(data, request.ListingAttributes)
+	url := fmt.Sprintf("%s/listings/%d", sc.listingServiceURL, listingID)
+	ctx, cancel := sc.withTimeout(ctx, OpUpdateListing)
+	defer cancel()
+	resp, err := sc.listingClient.PatchForm(ctx, url, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call listing service: %w", err)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	listing, err := decodeEnvelope[models.ListingResponse](listingServiceName, resp, "listing")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
-	var response ListingServiceResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	return &listing, nil
+}
+func (sc *ServiceClient) DeleteListing(ctx context.Context, listingID int) (*models.ListingResponse, error) {
+	url := fmt.Sprintf("%s/listings/%d", sc.listingServiceURL, listingID)
+	ctx, cancel := sc.withTimeout(ctx, OpDeleteListing)
+	defer cancel()
+	resp, err := sc.listingClient.Delete(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call listing service: %w", err)
+	}
+	defer resp.Body.Close()
+	listing, err := decodeEnvelope[models.ListingResponse](listingServiceName, resp, "listing")
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+func addListingFilterParams(params url.Values, filter models.ListingFilterRequest) {
+	if filter.ListingType != "" {
+		params.Set("listing_type", filter.ListingType)
+	}
+	if filter.PriceMin != nil {
+		params.Set("price_min", strconv.Itoa(*filter.PriceMin))
+	}
+	if filter.PriceMax != nil {
+		params.Set("price_max", strconv.Itoa(*filter.PriceMax))
 	}
-	if !response.Result {
-		return nil, fmt.Errorf("listing service error: %v", response.Error)
+	if filter.CreatedFrom != nil {
+		params.Set("created_from", strconv.FormatInt(*filter.CreatedFrom, 10))
 	}
-	if data, ok := response.Data.(map[string]interface{}); ok {
-		if listing, ok := data["listing"].(map[string]interface{}); ok {
-			return listing, nil
+	if filter.CreatedTo != nil {
+		params.Set("created_to", strconv.FormatInt(*filter.CreatedTo, 10))
+	}
+	if filter.SortBy != "" {
+		params.Set("sort_by", filter.SortBy)
+	}
+	if filter.SortOrder != "" {
+		params.Set("sort_order", filter.SortOrder)
+	}
+	if filter.Status != "" {
+		params.Set("status", filter.Status)
+	}
+}
+func addListingAttributeParams(data url.Values, attributes models.ListingAttributes) {
+	setString := func(key string, value *string) {
+		if value != nil {
+			data.Set(key, *value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			data.Set(key, strconv.Itoa(*value))
 		}
 	}
-	return nil, fmt.Errorf("unexpected response format")
+	setString("title", attributes.Title)
+	setString("description", attributes.Description)
+	setString("address", attributes.Address)
+	setString("city", attributes.City)
+	setString("postal_code", attributes.PostalCode)
+	setInt("bedrooms", attributes.Bedrooms)
+	setInt("bathrooms", attributes.Bathrooms)
+	if attributes.FloorArea != nil {
+		data.Set("floor_area", strconv.FormatFloat(*attributes.FloorArea, 'f', -1, 64))
+	}
+	setString("property_type", attributes.PropertyType)
 }
@@ -0,0 +1,155 @@
+package publicapi
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+const (
+	UserLookupBatch  = "batch"
+	UserLookupFanOut = "fanout"
+)
+// Partial failure policies decide what happens to a listing whose owner could
+// not be resolved.
+const (
+	PartialFailureFail = "fail"
+	PartialFailureNull = "null"
+	PartialFailureSkip = "skip"
+)
+type Config struct {
+	UserService           UpstreamConfig
+	ListingService        UpstreamConfig
+	UserLookupMode        string
+	UserLookupConcurrency int
+	PartialFailurePolicy  string
+	Timeouts              TimeoutConfig
+	UserCache             UserCacheConfig
+}
+type UpstreamConfig struct {
+	URL     string
+	Retry   RetryPolicy
+	Breaker CircuitBreakerConfig
+}
+// TimeoutConfig holds the deadline applied to each upstream call. PerOperation
+// overrides Default for the operations it lists (see the Op constants).
+type TimeoutConfig struct {
+	Default      time.Duration
+	PerOperation map[string]time.Duration
+}
+func (t TimeoutConfig) For(op string) time.Duration {
+	if timeout, ok := t.PerOperation[op]; ok {
+		return timeout
+	}
+	return t.Default
+}
+func NewConfig() *Config {
+	return &Config{
+		UserService:           newUpstreamConfig("USER_SERVICE", "http://localhost:8001"),
+		ListingService:        newUpstreamConfig("LISTING_SERVICE", "http://localhost:6000"),
+		UserLookupMode:        getEnv("USER_LOOKUP_MODE", UserLookupBatch),
+		UserLookupConcurrency: getEnvInt("USER_LOOKUP_CONCURRENCY", 10),
+		PartialFailurePolicy:  getEnv("PARTIAL_FAILURE_POLICY", PartialFailureSkip),
+		Timeouts:              newTimeoutConfig(),
+		UserCache: UserCacheConfig{
+			Size:        getEnvInt("USER_CACHE_SIZE", 1000),
+			TTL:         getEnvDuration("USER_CACHE_TTL", time.Minute),
+			NegativeTTL: getEnvDuration("USER_CACHE_NEGATIVE_TTL", 10*time.Second),
+		},
+	}
+}
+// Validate rejects settings that would otherwise quietly fall back to another
+// behaviour.
+func (c *Config) Validate() error {
+	switch c.UserLookupMode {
+	case UserLookupBatch, UserLookupFanOut:
+	default:
+		return fmt.Errorf("unsupported USER_LOOKUP_MODE %q, expected %s or %s", c.UserLookupMode, UserLookupBatch, UserLookupFanOut)
+	}
+	switch c.PartialFailurePolicy {
+	case PartialFailureFail, PartialFailureNull, PartialFailureSkip:
+	default:
+		return fmt.Errorf("unsupported PARTIAL_FAILURE_POLICY %q, expected %s, %s or %s", c.PartialFailurePolicy, PartialFailureFail, PartialFailureNull, PartialFailureSkip)
+	}
+	return nil
+}
+// newUpstreamConfig reads the settings of one upstream from <prefix>_URL,
+// <prefix>_RETRY_* and <prefix>_BREAKER_*.
+func newUpstreamConfig(prefix, defaultURL string) UpstreamConfig {
+	return UpstreamConfig{
+		URL: getEnv(prefix+"_URL", defaultURL),
+		Retry: RetryPolicy{
+			MaxAttempts:   getEnvInt(prefix+"_RETRY_MAX_ATTEMPTS", 3),
+			BaseDelay:     getEnvDuration(prefix+"_RETRY_BASE_DELAY", 100*time.Millisecond),
+			MaxDelay:      getEnvDuration(prefix+"_RETRY_MAX_DELAY", 2*time.Second),
+			Jitter:        getEnvFloat(prefix+"_RETRY_JITTER", 1),
+			RetryOnStatus: getEnvInts(prefix+"_RETRY_ON_STATUS", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}),
+		},
+		Breaker: CircuitBreakerConfig{
+			FailureThreshold: getEnvInt(prefix+"_BREAKER_FAILURE_THRESHOLD", 5),
+			OpenTimeout:      getEnvDuration(prefix+"_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+			HalfOpenMaxCalls: getEnvInt(prefix+"_BREAKER_HALF_OPEN_MAX_CALLS", 1),
+		},
+	}
+}
+// newTimeoutConfig reads UPSTREAM_TIMEOUT and its per-operation overrides,
+// e.g. UPSTREAM_TIMEOUT_GET_LISTINGS.
+func newTimeoutConfig() TimeoutConfig {
+	operations := []string{
+		OpGetUser, OpGetUsers, OpCreateUser, OpUpdateUser, OpDeleteUser,
+		OpGetListings, OpGetListing, OpCreateListing, OpUpdateListing, OpDeleteListing,
+	}
+	config := TimeoutConfig{
+		Default:      getEnvDuration("UPSTREAM_TIMEOUT", 10*time.Second),
+		PerOperation: make(map[string]time.Duration),
+	}
+	for _, op := range operations {
+		if timeout := getEnvDuration("UPSTREAM_TIMEOUT_"+strings.ToUpper(op), 0); timeout > 0 {
+			config.PerOperation[op] = timeout
+		}
+	}
+	return config
+}
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+func getEnvInts(key string, defaultValue []int) []int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	var values []int
+	for _, part := range strings.Split(raw, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	return values
+}
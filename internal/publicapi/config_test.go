@@ -0,0 +1,36 @@
+package publicapi
+import (
+	"strings"
+	"testing"
+)
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		setenv  map[string]string
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "fail policy", setenv: map[string]string{"PARTIAL_FAILURE_POLICY": PartialFailureFail}},
+		{name: "null policy", setenv: map[string]string{"PARTIAL_FAILURE_POLICY": PartialFailureNull}},
+		{name: "skip policy", setenv: map[string]string{"PARTIAL_FAILURE_POLICY": PartialFailureSkip}},
+		{name: "unknown policy", setenv: map[string]string{"PARTIAL_FAILURE_POLICY": "ignore"}, wantErr: `PARTIAL_FAILURE_POLICY "ignore"`},
+		{name: "batch lookups", setenv: map[string]string{"USER_LOOKUP_MODE": UserLookupBatch}},
+		{name: "fan-out lookups", setenv: map[string]string{"USER_LOOKUP_MODE": UserLookupFanOut}},
+		{name: "unknown lookup mode", setenv: map[string]string{"USER_LOOKUP_MODE": "fan-out"}, wantErr: `USER_LOOKUP_MODE "fan-out"`},
+		{name: "policy in the wrong case", setenv: map[string]string{"PARTIAL_FAILURE_POLICY": "Skip"}, wantErr: `PARTIAL_FAILURE_POLICY "Skip"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.setenv {
+				t.Setenv(key, value)
+			}
+			err := NewConfig().Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() = %v, want an error containing %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+type serviceEnvelope struct {
+	Result    bool            `json:"result"`
+	Version   string          `json:"version"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	Error     interface{}     `json:"error"`
+	ErrorCode string          `json:"error_code"`
+	Errors    interface{}     `json:"errors"`
+}
+func decodeEnvelope[T any](service string, resp *http.Response, key string) (T, error) {
+	var value T
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return value, fmt.Errorf("failed to read response body: %w", err)
+	}
+	var envelope serviceEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return value, &MalformedResponseError{Service: service, Reason: "invalid envelope", Err: err}
+	}
+	if envelope.Version != "" && envelope.Version != models.ResponseVersion {
+		return value, &MalformedResponseError{Service: service, Reason: fmt.Sprintf("unsupported envelope version %q", envelope.Version)}
+	}
+	if !envelope.Result {
+		return value, &UpstreamError{Service: service, StatusCode: resp.StatusCode, ErrorCode: envelope.ErrorCode, Message: envelope.errorMessage()}
+	}
+	raw, ok := envelope.payload(body, key)
+	if !ok {
+		return value, &MalformedResponseError{Service: service, Reason: fmt.Sprintf("missing %q in response", key)}
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return value, &MalformedResponseError{Service: service, Reason: fmt.Sprintf("invalid %q payload", key), Err: err}
+	}
+	return value, nil
+}
+func (e *serviceEnvelope) errorMessage() string {
+	var parts []string
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	for _, detail := range []interface{}{e.Error, e.Errors} {
+		if detail := errorDetail(detail); detail != "" {
+			parts = append(parts, detail)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown error"
+	}
+	return strings.Join(parts, ": ")
+}
+// errorDetail formats an error or errors field. Legacy responses list several
+// messages in an array, which read better joined than in Go's slice syntax.
+func errorDetail(detail interface{}) string {
+	if detail == nil {
+		return ""
+	}
+	list, ok := detail.([]interface{})
+	if !ok {
+		return fmt.Sprint(detail)
+	}
+	messages := make([]string, len(list))
+	for i, item := range list {
+		messages[i] = fmt.Sprint(item)
+	}
+	return strings.Join(messages, "; ")
+}
+// payload returns the value stored under key, or the whole payload when key is
+// empty. Legacy responses carry the payload at the top level instead of in data.
+func (e *serviceEnvelope) payload(body []byte, key string) (json.RawMessage, bool) {
+	payload := []byte(e.Data)
+	if len(payload) == 0 {
+		payload = body
+	}
+	if key == "" {
+		return payload, true
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, false
+	}
+	raw, ok := fields[key]
+	return raw, ok
+}
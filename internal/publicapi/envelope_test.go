@@ -0,0 +1,159 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+func newEnvelopeResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+func TestDecodeEnvelopePayload(t *testing.T) {
+	user := models.UserResponse{ID: 7, Name: "Ana", CreatedAt: 1700000000}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "versioned", body: `{"result":true,"version":"1","data":{"user":{"id":7,"name":"Ana","created_at":1700000000}}}`},
+		{name: "versioned with message", body: `{"result":true,"version":"1","message":"User created","data":{"user":{"id":7,"name":"Ana","created_at":1700000000}}}`},
+		{name: "legacy", body: `{"result":true,"user":{"id":7,"name":"Ana","created_at":1700000000}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeEnvelope[models.UserResponse](userServiceName, newEnvelopeResponse(http.StatusOK, tt.body), "user")
+			if err != nil || got != user {
+				t.Fatalf("decodeEnvelope() = %+v, %v, want %+v", got, err, user)
+			}
+		})
+	}
+}
+func TestDecodeEnvelopeWholePayload(t *testing.T) {
+	want := models.ListingListResponse{
+		Listings:           []models.ListingResponse{{ID: 3, UserID: 7, ListingType: "rent", Price: 4500}},
+		PaginationResponse: models.PaginationResponse{Total: 1, HasNext: true, NextCursor: "abc"},
+	}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "versioned", body: `{"result":true,"version":"1","data":{"listings":[{"id":3,"user_id":7,"listing_type":"rent","price":4500}],"total":1,"has_next":true,"next_cursor":"abc"}}`},
+		{name: "legacy", body: `{"result":true,"listings":[{"id":3,"user_id":7,"listing_type":"rent","price":4500}],"total":1,"has_next":true,"next_cursor":"abc"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeEnvelope[models.ListingListResponse](listingServiceName, newEnvelopeResponse(http.StatusOK, tt.body), "")
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Fatalf("decodeEnvelope() = %+v, %v, want %+v", got, err, want)
+			}
+		})
+	}
+}
+func TestDecodeEnvelopeErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantErrorCode string
+		wantMessage   string
+	}{
+		{
+			name:          "versioned with error and error code",
+			status:        http.StatusNotFound,
+			body:          `{"result":false,"version":"1","message":"Failed to get user","error":"user not found","error_code":"user_not_found","code":404}`,
+			wantErrorCode: models.ErrorCodeUserNotFound,
+			wantMessage:   "Failed to get user: user not found",
+		},
+		{
+			name:        "versioned without detail",
+			status:      http.StatusInternalServerError,
+			body:        `{"result":false,"version":"1","message":"Failed to get user","code":500}`,
+			wantMessage: "Failed to get user",
+		},
+		{
+			name:        "legacy errors array",
+			status:      http.StatusBadRequest,
+			body:        `{"result":false,"errors":["name is required","name is too long"]}`,
+			wantMessage: "name is required; name is too long",
+		},
+		{
+			name:        "legacy errors string",
+			status:      http.StatusNotFound,
+			body:        `{"result":false,"errors":"user not found"}`,
+			wantMessage: "user not found",
+		},
+		{
+			name:        "legacy message and errors",
+			status:      http.StatusBadRequest,
+			body:        `{"result":false,"message":"Validation failed","errors":["price must be positive"]}`,
+			wantMessage: "Validation failed: price must be positive",
+		},
+		{
+			name:        "error and errors",
+			status:      http.StatusBadRequest,
+			body:        `{"result":false,"error":"bad request","errors":["a","b"]}`,
+			wantMessage: "bad request: a; b",
+		},
+		{
+			name:        "empty errors array",
+			status:      http.StatusBadRequest,
+			body:        `{"result":false,"message":"Validation failed","errors":[]}`,
+			wantMessage: "Validation failed",
+		},
+		{
+			name:        "no detail at all",
+			status:      http.StatusBadGateway,
+			body:        `{"result":false}`,
+			wantMessage: "unknown error",
+		},
+		{
+			name:        "failure on a 200",
+			status:      http.StatusOK,
+			body:        `{"result":false,"errors":["user not found"]}`,
+			wantMessage: "user not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeEnvelope[models.UserResponse](userServiceName, newEnvelopeResponse(tt.status, tt.body), "user")
+			var upstream *UpstreamError
+			if !errors.As(err, &upstream) {
+				t.Fatalf("decodeEnvelope() = %v, want an UpstreamError", err)
+			}
+			want := UpstreamError{Service: userServiceName, StatusCode: tt.status, ErrorCode: tt.wantErrorCode, Message: tt.wantMessage}
+			if *upstream != want {
+				t.Errorf("error = %+v, want %+v", *upstream, want)
+			}
+		})
+	}
+}
+func TestDecodeEnvelopeMalformed(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantReason string
+	}{
+		{name: "not JSON", body: `<html>Bad Gateway</html>`, wantReason: "invalid envelope"},
+		{name: "not an object", body: `["user"]`, wantReason: "invalid envelope"},
+		{name: "unsupported version", body: `{"result":true,"version":"2","data":{"user":{"id":7}}}`, wantReason: `unsupported envelope version "2"`},
+		{name: "missing key", body: `{"result":true,"version":"1","data":{"users":[]}}`, wantReason: `missing "user" in response`},
+		{name: "legacy missing key", body: `{"result":true,"users":[]}`, wantReason: `missing "user" in response`},
+		{name: "null data", body: `{"result":true,"version":"1","data":null}`, wantReason: `missing "user" in response`},
+		{name: "data not an object", body: `{"result":true,"version":"1","data":[1,2]}`, wantReason: `missing "user" in response`},
+		{name: "payload of the wrong type", body: `{"result":true,"version":"1","data":{"user":{"id":"seven"}}}`, wantReason: `invalid "user" payload`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeEnvelope[models.UserResponse](userServiceName, newEnvelopeResponse(http.StatusOK, tt.body), "user")
+			var malformed *MalformedResponseError
+			if !errors.As(err, &malformed) {
+				t.Fatalf("decodeEnvelope() = %v, want a MalformedResponseError", err)
+			}
+			if malformed.Service != userServiceName || malformed.Reason != tt.wantReason {
+				t.Errorf("error = %+v, want reason %q", *malformed, tt.wantReason)
+			}
+		})
+	}
+}
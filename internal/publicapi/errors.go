@@ -0,0 +1,86 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+const (
+	userServiceName    = "user service"
+	listingServiceName = "listing service"
+)
+// upstreamLabel turns a service name into a metrics label, e.g. "user_service".
+func upstreamLabel(service string) string {
+	return strings.ReplaceAll(service, " ", "_")
+}
+var (
+	ErrUserNotFound           = errors.New("user not found")
+	ErrListingOwnerUnresolved = errors.New("listing owner could not be resolved")
+)
+type UpstreamError struct {
+	Service    string
+	StatusCode int
+	ErrorCode  string
+	Message    string
+}
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("%s error (status %d): %s", e.Service, e.StatusCode, e.Message)
+}
+type MalformedResponseError struct {
+	Service string
+	Reason  string
+	Err     error
+}
+func (e *MalformedResponseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("malformed %s response: %s: %v", e.Service, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("malformed %s response: %s", e.Service, e.Reason)
+}
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+func statusCodeForError(err error) int {
+	if errors.Is(err, ErrUserNotFound) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, ErrListingOwnerUnresolved) {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	var malformedErr *MalformedResponseError
+	if errors.As(err, &malformedErr) {
+		return http.StatusBadGateway
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		if upstreamErr.StatusCode >= 400 && upstreamErr.StatusCode < 500 {
+			return upstreamErr.StatusCode
+		}
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+func errorCodeForError(err error) string {
+	if errors.Is(err, ErrUserNotFound) {
+		return models.ErrorCodeUserNotFound
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.ErrorCode
+	}
+	return ""
+}
+// isUserNotFound reports whether the user service said the user does not
+// exist. A bare 404, e.g. from a misrouted request, does not count.
+func isUserNotFound(err error) bool {
+	var upstreamErr *UpstreamError
+	return errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusNotFound && upstreamErr.ErrorCode == models.ErrorCodeUserNotFound
+}
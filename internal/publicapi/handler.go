@@ -1,7 +1,10 @@
 package publicapi
 import (
+	"99-backend-exercise/internal/models"
 	"99-backend-exercise/pkg/utils"
+	"context"
 	"net/http"
+	"strconv"
 	"github.com/gin-gonic/gin"
 )
 type Handler struct {
@@ -13,17 +16,38 @@ func NewHandler(publicAPIService Service) *Handler {
 	}
 }
 type PublicListingsRequest struct {
-	PageNum  int  `form:"page_num" json:"page_num"`
-	PageSize int  `form:"page_size" json:"page_size"`
-	UserID   *int `form:"user_id" json:"user_id,omitempty"`
+	PageNum  int    `form:"page_num" json:"page_num"`
+	PageSize int    `form:"page_size" json:"page_size"`
+	Cursor   string `form:"cursor" json:"cursor,omitempty"`
+	Limit    int    `form:"limit" json:"limit,omitempty" binding:"omitempty,min=1,max=100"`
+	UserID   *int   `form:"user_id" json:"user_id,omitempty"`
+	models.ListingFilterRequest
 }
 type CreateUserRequest struct {
 	Name string `json:"name" binding:"required"`
 }
+type UpdateUserRequest struct {
+	Name *string `json:"name" binding:"omitempty,min=1"`
+}
 type CreateListingRequest struct {
 	UserID      int    `json:"user_id" binding:"required"`
 	ListingType string `json:"listing_type" binding:"required,oneof=rent sale"`
 	Price       int    `json:"price" binding:"required,min=1"`
+	Status      string `json:"status,omitempty" binding:"omitempty,oneof=draft active"`
+	models.ListingAttributes
+}
+type UpdateListingRequest struct {
+	Price *int `json:"price" binding:"omitempty,min=1"`
+	models.ListingAttributes
+}
+// writeContext returns the context of an incoming write, carrying its
+// Idempotency-Key header (if any) so that the upstream write may be retried.
+func writeContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if key := c.GetHeader(IdempotencyKeyHeader); key != "" {
+		ctx = WithIdempotencyKey(ctx, key)
+	}
+	return ctx
 }
 func (h *Handler) GetListings(c *gin.Context) {
 	var request PublicListingsRequest
@@ -31,21 +55,34 @@ func (h *Handler) GetListings(c *gin.Context) {
 		utils.RespondWithValidationError(c, err)
 		return
 	}
+	if err := request.Validate(); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	if request.Status == "" {
+		request.Status = models.ListingStatusActive
+	}
 	if request.PageNum <= 0 {
 		request.PageNum = 1
 	}
 	if request.PageSize <= 0 {
 		request.PageSize = 10
 	}
-	listings, err := h.publicAPIService.GetListings(request.PageNum, request.PageSize, request.UserID)
+	listings, err := h.publicAPIService.GetListings(c.Request.Context(), models.GetListingsRequest{
+		PaginationRequest: models.PaginationRequest{
+			PageNum:  request.PageNum,
+			PageSize: request.PageSize,
+			Cursor:   request.Cursor,
+			Limit:    request.Limit,
+		},
+		UserID:               request.UserID,
+		ListingFilterRequest: request.ListingFilterRequest,
+	})
 	if err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get listings", err)
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get listings", err)
 		return
 	}
-	response := map[string]interface{}{
-		"listings": listings,
-	}
-	utils.RespondWithSuccess(c, response)
+	utils.RespondWithSuccess(c, listings)
 }
 func (h *Handler) CreateUser(c *gin.Context) {
 	var request CreateUserRequest
@@ -53,9 +90,44 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		utils.RespondWithValidationError(c, err)
 		return
 	}
-	user, err := h.publicAPIService.CreateUser(request.Name)
+	user, err := h.publicAPIService.CreateUser(writeContext(c), request.Name)
 	if err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create user", err)
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to create user", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+func (h *Handler) UpdateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	var request UpdateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	user, err := h.publicAPIService.UpdateUser(writeContext(c), userID, request.Name)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to update user", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"user": user,
+	})
+}
+func (h *Handler) DeleteUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	user, err := h.publicAPIService.DeleteUser(writeContext(c), userID)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to delete user", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -68,9 +140,68 @@ func (h *Handler) CreateListing(c *gin.Context) {
 		utils.RespondWithValidationError(c, err)
 		return
 	}
-	listing, err := h.publicAPIService.CreateListing(request.UserID, request.ListingType, request.Price)
+	listing, err := h.publicAPIService.CreateListing(writeContext(c), models.CreateListingRequest{
+		UserID:            request.UserID,
+		ListingType:       request.ListingType,
+		Price:             request.Price,
+		Status:            request.Status,
+		ListingAttributes: request.ListingAttributes,
+	})
+	if err != nil {
+		utils.RespondWithErrorCode(c, statusCodeForError(err), errorCodeForError(err), "Failed to create listing", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"listing": listing,
+	})
+}
+func (h *Handler) GetListing(c *gin.Context) {
+	listingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	listing, err := h.publicAPIService.GetListing(c.Request.Context(), listingID)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get listing", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"listing": listing,
+	})
+}
+func (h *Handler) UpdateListing(c *gin.Context) {
+	listingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	var request UpdateListingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	listing, err := h.publicAPIService.UpdateListing(writeContext(c), listingID, models.UpdateListingRequest{
+		Price:             request.Price,
+		ListingAttributes: request.ListingAttributes,
+	})
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to update listing", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"listing": listing,
+	})
+}
+func (h *Handler) DeleteListing(c *gin.Context) {
+	listingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid listing ID", err)
+		return
+	}
+	listing, err := h.publicAPIService.DeleteListing(writeContext(c), listingID)
 	if err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create listing", err)
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to delete listing", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
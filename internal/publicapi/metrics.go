@@ -0,0 +1,158 @@
+package publicapi
+import (
+	"99-backend-exercise/pkg/metrics"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+type operationContextKey struct{}
+func withOperation(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, operationContextKey{}, op)
+}
+func operation(ctx context.Context) string {
+	op, ok := ctx.Value(operationContextKey{}).(string)
+	if !ok {
+		return "unknown"
+	}
+	return op
+}
+type instrumentedHTTPClient struct {
+	next     HTTPClient
+	upstream string
+}
+// NewInstrumentedHTTPClient reports the outcome and latency of every call to
+// upstream, labelled with the ServiceClient operation that made it.
+func NewInstrumentedHTTPClient(next HTTPClient, upstream string) HTTPClient {
+	return &instrumentedHTTPClient{
+		next:     next,
+		upstream: upstream,
+	}
+}
+func (c *instrumentedHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, func(ctx context.Context) (*http.Response, error) {
+		return c.next.Get(ctx, url)
+	})
+}
+func (c *instrumentedHTTPClient) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, func(ctx context.Context) (*http.Response, error) {
+		return c.next.PostForm(ctx, url, data)
+	})
+}
+func (c *instrumentedHTTPClient) PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, func(ctx context.Context) (*http.Response, error) {
+		return c.next.PatchForm(ctx, url, data)
+	})
+}
+func (c *instrumentedHTTPClient) Delete(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, func(ctx context.Context) (*http.Response, error) {
+		return c.next.Delete(ctx, url)
+	})
+}
+func (c *instrumentedHTTPClient) do(ctx context.Context, call func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	op := operation(ctx)
+	ctx, span := otel.Tracer("99-backend-exercise/internal/publicapi").Start(ctx, c.upstream+" "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("upstream", c.upstream), attribute.String("operation", op)),
+	)
+	defer span.End()
+	start := time.Now()
+	resp, err := call(ctx)
+	outcome := callOutcome(resp, err)
+	metrics.ObserveUpstreamCall(c.upstream, op, outcome, time.Since(start))
+	span.SetAttributes(attribute.String("outcome", outcome))
+	if outcome != "success" && outcome != "client_error" {
+		span.SetStatus(codes.Error, outcome)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return resp, err
+}
+func callOutcome(resp *http.Response, err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case err != nil:
+		return "error"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "server_error"
+	case resp.StatusCode >= http.StatusBadRequest:
+		return "client_error"
+	default:
+		return "success"
+	}
+}
+// RegisterMetrics exports the circuit breaker, user cache and partial failure
+// counters kept by the public API.
+func RegisterMetrics(serviceClient *ServiceClient, userCache UserCache, service Service) {
+	for _, breaker := range []*CircuitBreaker{serviceClient.userBreaker, serviceClient.listingBreaker} {
+		labels := prometheus.Labels{"upstream": upstreamLabel(breaker.name)}
+		prometheus.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name:        "circuit_breaker_state",
+				Help:        "Circuit breaker state: 0 closed, 1 half-open, 2 open.",
+				ConstLabels: labels,
+			}, func() float64 {
+				return circuitStateValue(breaker.Status().State)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name:        "circuit_breaker_opens_total",
+				Help:        "Times the circuit breaker opened.",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(breaker.Status().Opens)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name:        "circuit_breaker_rejected_total",
+				Help:        "Calls rejected by an open circuit breaker.",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(breaker.Status().Rejected)
+			}),
+		)
+	}
+	cacheCounter := func(name, help string, value func(CacheStats) int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return float64(value(userCache.Stats()))
+		})
+	}
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "user_cache_size",
+			Help: "Users currently held in the user cache.",
+		}, func() float64 {
+			return float64(userCache.Stats().Size)
+		}),
+		cacheCounter("user_cache_hits_total", "User cache hits.", func(s CacheStats) int64 { return s.Hits }),
+		cacheCounter("user_cache_negative_hits_total", "User cache hits on users known to be missing.", func(s CacheStats) int64 { return s.NegativeHits }),
+		cacheCounter("user_cache_misses_total", "User cache misses.", func(s CacheStats) int64 { return s.Misses }),
+		cacheCounter("user_cache_evictions_total", "Users evicted from the user cache to make room.", func(s CacheStats) int64 { return s.Evictions }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "listing_owner_partial_failures_total",
+			Help: "Listings whose owner could not be resolved.",
+		}, func() float64 {
+			return float64(service.PartialFailures())
+		}),
+	)
+}
+func circuitStateValue(state string) float64 {
+	switch state {
+	case CircuitHalfOpen:
+		return 1
+	case CircuitOpen:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,106 @@
+package publicapi
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+// RetryPolicy controls how calls to one upstream are retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay; Jitter (0 to 1) is the fraction
+// of each delay that is randomised away.
+type RetryPolicy struct {
+	MaxAttempts   int
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	Jitter        float64
+	RetryOnStatus []int
+}
+func (p RetryPolicy) shouldRetryStatus(statusCode int) bool {
+	for _, status := range p.RetryOnStatus {
+		if status == statusCode {
+			return true
+		}
+	}
+	return false
+}
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	return delay - time.Duration(rand.Float64()*p.Jitter*float64(delay))
+}
+// IdempotencyKeyHeader carries the client's idempotency key. The user and
+// listing services apply a write with a given key only once.
+const IdempotencyKeyHeader = "Idempotency-Key"
+type idempotencyKeyContextKey struct{}
+// WithIdempotencyKey marks the writes made with ctx as safe to retry. The key is
+// forwarded to the upstream in the Idempotency-Key header.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+type retryingHTTPClient struct {
+	next   HTTPClient
+	policy RetryPolicy
+}
+// NewRetryingHTTPClient wraps next so that GETs are retried according to
+// policy. Other methods are only retried when the call carries an idempotency
+// key: a failed attempt may still have been applied, and only the key lets the
+// upstream recognise the repeat.
+func NewRetryingHTTPClient(next HTTPClient, policy RetryPolicy) HTTPClient {
+	return &retryingHTTPClient{
+		next:   next,
+		policy: policy,
+	}
+}
+func (c *retryingHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, true, func() (*http.Response, error) {
+		return c.next.Get(ctx, url)
+	})
+}
+func (c *retryingHTTPClient) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, idempotencyKey(ctx) != "", func() (*http.Response, error) {
+		return c.next.PostForm(ctx, url, data)
+	})
+}
+func (c *retryingHTTPClient) PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.do(ctx, idempotencyKey(ctx) != "", func() (*http.Response, error) {
+		return c.next.PatchForm(ctx, url, data)
+	})
+}
+func (c *retryingHTTPClient) Delete(ctx context.Context, url string) (*http.Response, error) {
+	return c.do(ctx, idempotencyKey(ctx) != "", func() (*http.Response, error) {
+		return c.next.Delete(ctx, url)
+	})
+}
+func (c *retryingHTTPClient) do(ctx context.Context, retryable bool, call func() (*http.Response, error)) (*http.Response, error) {
+	attempts := c.policy.MaxAttempts
+	if !retryable || attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := call()
+		lastAttempt := attempt+1 >= attempts
+		if err == nil && (lastAttempt || !c.policy.shouldRetryStatus(resp.StatusCode)) {
+			return resp, nil
+		}
+		if err != nil && (lastAttempt || ctx.Err() != nil) {
+			return nil, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(c.policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
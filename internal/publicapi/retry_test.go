@@ -0,0 +1,203 @@
+package publicapi
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      2 * time.Millisecond,
+		Jitter:        1,
+		RetryOnStatus: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+func TestRetryingHTTPClientGet(t *testing.T) {
+	ok := stubResult{status: http.StatusOK}
+	unavailable := stubResult{status: http.StatusServiceUnavailable}
+	networkError := stubResult{err: errConnectionRefused}
+	tests := []struct {
+		name        string
+		maxAttempts int
+		results     []stubResult
+		wantCalls   int
+		wantStatus  int
+		wantErr     error
+	}{
+		{name: "success", maxAttempts: 3, results: []stubResult{ok}, wantCalls: 1, wantStatus: http.StatusOK},
+		{name: "retryable status then success", maxAttempts: 3, results: []stubResult{unavailable, ok}, wantCalls: 2, wantStatus: http.StatusOK},
+		{name: "every retryable status", maxAttempts: 4, results: []stubResult{{status: http.StatusBadGateway}, unavailable, {status: http.StatusGatewayTimeout}, ok}, wantCalls: 4, wantStatus: http.StatusOK},
+		{name: "retryable status on every attempt", maxAttempts: 3, results: []stubResult{unavailable}, wantCalls: 3, wantStatus: http.StatusServiceUnavailable},
+		{name: "status not in RetryOnStatus", maxAttempts: 3, results: []stubResult{{status: http.StatusInternalServerError}, ok}, wantCalls: 1, wantStatus: http.StatusInternalServerError},
+		{name: "client error", maxAttempts: 3, results: []stubResult{{status: http.StatusNotFound}, ok}, wantCalls: 1, wantStatus: http.StatusNotFound},
+		{name: "network error then success", maxAttempts: 3, results: []stubResult{networkError, ok}, wantCalls: 2, wantStatus: http.StatusOK},
+		{name: "network error on every attempt", maxAttempts: 3, results: []stubResult{networkError}, wantCalls: 3, wantErr: errConnectionRefused},
+		{name: "single attempt", maxAttempts: 1, results: []stubResult{unavailable, ok}, wantCalls: 1, wantStatus: http.StatusServiceUnavailable},
+		{name: "no attempts configured", maxAttempts: 0, results: []stubResult{unavailable, ok}, wantCalls: 1, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testRetryPolicy()
+			policy.MaxAttempts = tt.maxAttempts
+			stub := newStubHTTPClient(tt.results...)
+			resp, err := NewRetryingHTTPClient(stub, policy).Get(context.Background(), "http://user-service/users/1")
+			if stub.Calls() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", stub.Calls(), tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || resp != nil {
+					t.Fatalf("Get() = %v, %v, want error %v", resp, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Get() = %v, %v, want status %d", resp, err, tt.wantStatus)
+			}
+			for i, body := range stub.bodies {
+				last := i == len(stub.bodies)-1
+				if body.closed == last {
+					t.Errorf("body %d closed = %v, want only discarded responses closed", i, body.closed)
+				}
+			}
+		})
+	}
+}
+// writeCalls make one write each against the user service at base.
+var writeCalls = []struct {
+	name string
+	call func(ctx context.Context, client HTTPClient, base string) (*http.Response, error)
+}{
+	{name: "post", call: func(ctx context.Context, client HTTPClient, base string) (*http.Response, error) {
+		return client.PostForm(ctx, base+"/users", url.Values{"name": {"a"}})
+	}},
+	{name: "patch", call: func(ctx context.Context, client HTTPClient, base string) (*http.Response, error) {
+		return client.PatchForm(ctx, base+"/users/1", url.Values{"name": {"a"}})
+	}},
+	{name: "delete", call: func(ctx context.Context, client HTTPClient, base string) (*http.Response, error) {
+		return client.Delete(ctx, base+"/users/1")
+	}},
+}
+func TestRetryingHTTPClientDoesNotRetryWritesWithoutKey(t *testing.T) {
+	for _, tt := range writeCalls {
+		for name, result := range map[string]stubResult{"unavailable": {status: http.StatusServiceUnavailable}, "timeout": {err: context.DeadlineExceeded}} {
+			t.Run(tt.name+" "+name, func(t *testing.T) {
+				stub := newStubHTTPClient(result, stubResult{status: http.StatusOK})
+				tt.call(context.Background(), NewRetryingHTTPClient(stub, testRetryPolicy()), "http://user-service")
+				if stub.Calls() != 1 {
+					t.Errorf("calls = %d, want 1: writes without an idempotency key must never be retried", stub.Calls())
+				}
+			})
+		}
+	}
+}
+func TestRetryingHTTPClientRetriesWritesWithKey(t *testing.T) {
+	ctx := WithIdempotencyKey(context.Background(), "create-user-1")
+	for _, tt := range writeCalls {
+		for name, result := range map[string]stubResult{"unavailable": {status: http.StatusServiceUnavailable}, "network error": {err: errConnectionRefused}} {
+			t.Run(tt.name+" "+name, func(t *testing.T) {
+				stub := newStubHTTPClient(result, stubResult{status: http.StatusOK})
+				resp, err := tt.call(ctx, NewRetryingHTTPClient(stub, testRetryPolicy()), "http://user-service")
+				if err != nil || resp.StatusCode != http.StatusOK {
+					t.Fatalf("call = %v, %v, want status 200", resp, err)
+				}
+				if stub.Calls() != 2 {
+					t.Errorf("calls = %d, want 2", stub.Calls())
+				}
+			})
+		}
+	}
+}
+func TestDefaultHTTPClientForwardsIdempotencyKeyOnWrites(t *testing.T) {
+	received := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received[r.Method] = r.Header.Get(IdempotencyKeyHeader)
+	}))
+	defer server.Close()
+	ctx := WithIdempotencyKey(context.Background(), "create-user-1")
+	client := NewHTTPClient()
+	for _, tt := range writeCalls {
+		resp, err := tt.call(ctx, client, server.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := client.Get(ctx, server.URL+"/users/1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	want := map[string]string{
+		http.MethodPost:   "create-user-1",
+		http.MethodPatch:  "create-user-1",
+		http.MethodDelete: "create-user-1",
+		http.MethodGet:    "",
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("%s header by method = %q, want %q", IdempotencyKeyHeader, received, want)
+	}
+}
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		wantMax time.Duration
+	}{
+		{name: "first retry", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}, attempt: 0, wantMax: 100 * time.Millisecond},
+		{name: "grows exponentially", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}, attempt: 3, wantMax: 800 * time.Millisecond},
+		{name: "capped at MaxDelay", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}, attempt: 5, wantMax: 2 * time.Second},
+		{name: "shift overflow is capped", policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}, attempt: 70, wantMax: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		for _, jitter := range []float64{0, 0.5, 1} {
+			t.Run(fmt.Sprintf("%s jitter %v", tt.name, jitter), func(t *testing.T) {
+				policy := tt.policy
+				policy.Jitter = jitter
+				wantMin := tt.wantMax - time.Duration(jitter*float64(tt.wantMax))
+				for i := 0; i < 1000; i++ {
+					delay := policy.delay(tt.attempt)
+					if delay < wantMin || delay > tt.wantMax {
+						t.Fatalf("delay(%d) with jitter %v = %v, want within [%v, %v]", tt.attempt, jitter, delay, wantMin, tt.wantMax)
+					}
+				}
+			})
+		}
+	}
+}
+func TestRetryingHTTPClientStopsWhenCanceledDuringBackoff(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.BaseDelay, policy.MaxDelay, policy.Jitter = time.Hour, time.Hour, 0
+	stub := newStubHTTPClient(stubResult{status: http.StatusServiceUnavailable})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := NewRetryingHTTPClient(stub, policy).Get(ctx, "http://user-service/users/1")
+	if !errors.Is(err, context.DeadlineExceeded) || resp != nil {
+		t.Fatalf("Get() = %v, %v, want context.DeadlineExceeded", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() returned after %v, want it to stop backing off when ctx is done", elapsed)
+	}
+	if stub.Calls() != 1 || !stub.bodies[0].closed {
+		t.Errorf("calls = %d, want 1 with its response body closed", stub.Calls())
+	}
+}
+func TestRetryingHTTPClientDoesNotRetryAfterContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stub := newStubHTTPClient(stubResult{err: context.Canceled}, stubResult{status: http.StatusOK})
+	if _, err := NewRetryingHTTPClient(stub, testRetryPolicy()).Get(ctx, "http://user-service/users/1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get() = %v, want context.Canceled", err)
+	}
+	if stub.Calls() != 1 {
+		t.Errorf("calls = %d, want 1", stub.Calls())
+	}
+}
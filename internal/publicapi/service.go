@@ -1,61 +1,262 @@
 package publicapi
 import (
 	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/logging"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
 )
 type Service interface {
-	GetListings(pageNum, pageSize int, userID *int) ([]models.PublicListingResponse, error)
-	CreateUser(name string) (map[string]interface{}, error)
-	CreateListing(userID int, listingType string, price int) (map[string]interface{}, error)
+	GetListings(ctx context.Context, request models.GetListingsRequest) (*models.PublicListingListResponse, error)
+	CreateUser(ctx context.Context, name string) (*models.UserResponse, error)
+	UpdateUser(ctx context.Context, userID int, name *string) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, userID int) (*models.UserResponse, error)
+	GetListing(ctx context.Context, listingID int) (*models.PublicListingResponse, error)
+	CreateListing(ctx context.Context, request models.CreateListingRequest) (*models.ListingResponse, error)
+	UpdateListing(ctx context.Context, listingID int, request models.UpdateListingRequest) (*models.ListingResponse, error)
+	DeleteListing(ctx context.Context, listingID int) (*models.ListingResponse, error)
+	PartialFailures() int64
 }
 type service struct {
-	serviceClient *ServiceClient
+	serviceClient   *ServiceClient
+	userCache       UserCache
+	config          *Config
+	logger          *slog.Logger
+	partialFailures atomic.Int64
 }
-func NewService(serviceClient *ServiceClient) Service {
+func NewService(serviceClient *ServiceClient, userCache UserCache, config *Config) Service {
 	return &service{
 		serviceClient: serviceClient,
+		userCache:     userCache,
+		config:        config,
+		logger:        logging.Logger("publicapi"),
 	}
 }
-func (s *service) GetListings(pageNum, pageSize int, userID *int) ([]models.PublicListingResponse, error) {
-	listings, err := s.serviceClient.GetListings(pageNum, pageSize, userID)
+func (s *service) GetListings(ctx context.Context, request models.GetListingsRequest) (*models.PublicListingListResponse, error) {
+	page, err := s.serviceClient.GetListings(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get listings: %w", err)
 	}
-	var result []models.PublicListingResponse
-	for _, listingData := range listings {
-		listing, ok := listingData.(map[string]interface{})
-		if !ok {
+	listings := page.Listings
+	users, lookupErrs := s.getUsers(ctx, uniqueUserIDs(listings))
+	result := make([]models.PublicListingResponse, 0, len(listings))
+	var warnings []models.PublicListingWarning
+	for _, listing := range listings {
+		user, ok := users[listing.UserID]
+		if ok {
+			result = append(result, toPublicListingResponse(listing, &user))
 			continue
 		}
-		userIDFloat, ok := listing["user_id"].(float64)
+		lookupErr := lookupErrs[listing.UserID]
+		s.partialFailures.Add(1)
+		s.logger.WarnContext(ctx, "listing owner could not be resolved", "listing_id", listing.ID, "user_id", listing.UserID, "policy", s.config.PartialFailurePolicy, "error", lookupErr)
+		switch s.config.PartialFailurePolicy {
+		case PartialFailureFail:
+			return nil, fmt.Errorf("%w: listing %d: %v", ErrListingOwnerUnresolved, listing.ID, lookupErr)
+		case PartialFailureNull:
+			result = append(result, toPublicListingResponse(listing, nil))
+			warnings = append(warnings, models.PublicListingWarning{
+				ListingID: listing.ID,
+				UserID:    listing.UserID,
+				Message:   fmt.Sprintf("owner could not be resolved: %v", lookupErr),
+			})
+		default:
+			warnings = append(warnings, models.PublicListingWarning{
+				ListingID: listing.ID,
+				UserID:    listing.UserID,
+				Message:   fmt.Sprintf("listing omitted, owner could not be resolved: %v", lookupErr),
+			})
+		}
+	}
+	return &models.PublicListingListResponse{
+		Listings:           result,
+		PaginationResponse: page.PaginationResponse,
+		Warnings:           warnings,
+	}, nil
+}
+func (s *service) PartialFailures() int64 {
+	return s.partialFailures.Load()
+}
+func (s *service) GetListing(ctx context.Context, listingID int) (*models.PublicListingResponse, error) {
+	listing, err := s.serviceClient.GetListing(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing: %w", err)
+	}
+	users, lookupErrs := s.getUsers(ctx, []int{listing.UserID})
+	user, ok := users[listing.UserID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get listing owner: %w", lookupErrs[listing.UserID])
+	}
+	response := toPublicListingResponse(*listing, &user)
+	return &response, nil
+}
+func toPublicListingResponse(listing models.ListingResponse, user *models.UserResponse) models.PublicListingResponse {
+	return models.PublicListingResponse{
+		ID:                listing.ID,
+		ListingType:       listing.ListingType,
+		Price:             listing.Price,
+		ListingAttributes: listing.ListingAttributes,
+		Status:            listing.Status,
+		PreviousPrice:     listing.PreviousPrice,
+		PriceChangedAt:    listing.PriceChangedAt,
+		CreatedAt:         listing.CreatedAt,
+		UpdatedAt:         listing.UpdatedAt,
+		User:              user,
+	}
+}
+// getUsers resolves the given users through the user cache. Users that could
+// not be resolved are missing from users, with the reason in lookupErrs.
+func (s *service) getUsers(ctx context.Context, userIDs []int) (users map[int]models.UserResponse, lookupErrs map[int]error) {
+	users = make(map[int]models.UserResponse, len(userIDs))
+	lookupErrs = make(map[int]error)
+	var uncached []int
+	for _, userID := range userIDs {
+		entry, ok := s.userCache.Get(ctx, userID)
 		if !ok {
+			uncached = append(uncached, userID)
 			continue
 		}
-		listingUserID := int(userIDFloat)
-		user, err := s.serviceClient.GetUser(listingUserID)
-		if err != nil {
+		if entry.User == nil {
+			lookupErrs[userID] = &UpstreamError{Service: userServiceName, StatusCode: http.StatusNotFound, ErrorCode: models.ErrorCodeUserNotFound, Message: "user not found (cached)"}
 			continue
 		}
-		publicListing := models.PublicListingResponse{
-			ID:          int(listing["id"].(float64)),
-			ListingType: listing["listing_type"].(string),
-			Price:       int(listing["price"].(float64)),
-			CreatedAt:   int64(listing["created_at"].(float64)),
-			UpdatedAt:   int64(listing["updated_at"].(float64)),
-			User: models.UserResponse{
-				ID:        int(user["id"].(float64)),
-				Name:      user["name"].(string),
-				CreatedAt: int64(user["created_at"].(float64)),
-				UpdatedAt: int64(user["updated_at"].(float64)),
-			},
+		users[userID] = *entry.User
+	}
+	if len(uncached) == 0 {
+		return users, lookupErrs
+	}
+	var fetched map[int]models.UserResponse
+	var fetchErrs map[int]error
+	if s.config.UserLookupMode == UserLookupFanOut {
+		fetched, fetchErrs = s.getUsersConcurrently(ctx, uncached)
+	} else {
+		fetched, fetchErrs = s.getUsersInBatch(ctx, uncached)
+	}
+	for _, userID := range uncached {
+		if user, ok := fetched[userID]; ok {
+			users[userID] = user
+			s.userCache.Set(ctx, userID, UserCacheEntry{User: &user})
+			continue
+		}
+		err := fetchErrs[userID]
+		if err == nil {
+			err = fmt.Errorf("%w: user %d", ErrUserNotFound, userID)
+		}
+		// Only cache answers that say the user does not exist. Timeouts, open
+		// circuits and 5xx responses must not hide the user until NegativeTTL.
+		if errors.Is(err, ErrUserNotFound) || isUserNotFound(err) {
+			s.userCache.Set(ctx, userID, UserCacheEntry{})
+		}
+		lookupErrs[userID] = err
+	}
+	return users, lookupErrs
+}
+// getUsersInBatch fetches users with a single call. The user service leaves
+// out users that do not exist, so they are missing from both results.
+func (s *service) getUsersInBatch(ctx context.Context, userIDs []int) (map[int]models.UserResponse, map[int]error) {
+	usersByID := make(map[int]models.UserResponse, len(userIDs))
+	lookupErrs := make(map[int]error)
+	batch, err := s.serviceClient.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		for _, userID := range userIDs {
+			lookupErrs[userID] = err
 		}
-		result = append(result, publicListing)
+		return usersByID, lookupErrs
+	}
+	for _, user := range batch {
+		usersByID[user.ID] = user
+	}
+	return usersByID, lookupErrs
+}
+func (s *service) getUsersConcurrently(ctx context.Context, userIDs []int) (map[int]models.UserResponse, map[int]error) {
+	concurrency := s.config.UserLookupConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	usersByID := make(map[int]models.UserResponse, len(userIDs))
+	lookupErrs := make(map[int]error)
+	for _, userID := range userIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(userID int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			user, err := s.serviceClient.GetUser(ctx, userID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lookupErrs[userID] = err
+				return
+			}
+			usersByID[userID] = *user
+		}(userID)
+	}
+	wg.Wait()
+	return usersByID, lookupErrs
+}
+func uniqueUserIDs(listings []models.ListingResponse) []int {
+	seen := make(map[int]bool, len(listings))
+	var userIDs []int
+	for _, listing := range listings {
+		if seen[listing.UserID] {
+			continue
+		}
+		seen[listing.UserID] = true
+		userIDs = append(userIDs, listing.UserID)
+	}
+	return userIDs
+}
+func (s *service) CreateUser(ctx context.Context, name string) (*models.UserResponse, error) {
+	user, err := s.serviceClient.CreateUser(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.userCache.Delete(ctx, user.ID)
+	return user, nil
+}
+// UpdateUser and DeleteUser drop the cached user even when the call fails, as
+// the change may still have been applied upstream.
+func (s *service) UpdateUser(ctx context.Context, userID int, name *string) (*models.UserResponse, error) {
+	defer s.userCache.Delete(ctx, userID)
+	return s.serviceClient.UpdateUser(ctx, userID, name)
+}
+func (s *service) DeleteUser(ctx context.Context, userID int) (*models.UserResponse, error) {
+	defer s.userCache.Delete(ctx, userID)
+	return s.serviceClient.DeleteUser(ctx, userID)
+}
+func (s *service) CreateListing(ctx context.Context, request models.CreateListingRequest) (*models.ListingResponse, error) {
+	if err := s.verifyUser(ctx, request.UserID); err != nil {
+		return nil, err
+	}
+	return s.serviceClient.CreateListing(ctx, request)
+}
+// verifyUser always asks the user service, bypassing the cache, so listings
+// are never created for a user that was just deleted.
+func (s *service) verifyUser(ctx context.Context, userID int) error {
+	user, err := s.serviceClient.GetUser(ctx, userID)
+	if isUserNotFound(err) {
+		return fmt.Errorf("%w: user %d does not exist", ErrUserNotFound, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify user: %w", err)
+	}
+	if user.DeletedAt != nil {
+		return fmt.Errorf("%w: user %d has been deleted", ErrUserNotFound, userID)
 	}
-	return result, nil
+	return nil
 }
-func (s *service) CreateUser(name string) (map[string]interface{}, error) {
-	return s.serviceClient.CreateUser(name)
+func (s *service) UpdateListing(ctx context.Context, listingID int, request models.UpdateListingRequest) (*models.ListingResponse, error) {
+	return s.serviceClient.UpdateListing(ctx, listingID, request)
 }
-func (s *service) CreateListing(userID int, listingType string, price int) (map[string]interface{}, error) {
-	return s.serviceClient.CreateListing(userID, listingType, price)
+func (s *service) DeleteListing(ctx context.Context, listingID int) (*models.ListingResponse, error) {
+	return s.serviceClient.DeleteListing(ctx, listingID)
 }
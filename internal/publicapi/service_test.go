@@ -0,0 +1,161 @@
+package publicapi
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+// fakeBackends serves the listing and user service endpoints the public API
+// reads listings and their owners from. Users missing from users are unknown
+// to the user service.
+type fakeBackends struct {
+	listings    []models.ListingResponse
+	users       map[int]models.UserResponse
+	mu          sync.Mutex
+	userQueries []string
+}
+func (b *fakeBackends) start(t *testing.T) *Config {
+	t.Helper()
+	listingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeEnvelope(w, http.StatusOK, models.Response{Data: models.ListingListResponse{
+			Listings:           b.listings,
+			PaginationResponse: models.PaginationResponse{Total: int64(len(b.listings))},
+		}})
+	}))
+	t.Cleanup(listingServer.Close)
+	userServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		b.userQueries = append(b.userQueries, r.URL.RequestURI())
+		b.mu.Unlock()
+		if r.URL.Path == "/users" {
+			found := []models.UserResponse{}
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				userID, _ := strconv.Atoi(id)
+				if user, ok := b.users[userID]; ok {
+					found = append(found, user)
+				}
+			}
+			writeEnvelope(w, http.StatusOK, models.Response{Data: map[string]interface{}{"users": found}})
+			return
+		}
+		userID, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/users/"))
+		user, ok := b.users[userID]
+		if !ok {
+			writeEnvelope(w, http.StatusNotFound, models.Response{Message: "Failed to get user", Error: "user not found", ErrorCode: models.ErrorCodeUserNotFound, Code: http.StatusNotFound})
+			return
+		}
+		writeEnvelope(w, http.StatusOK, models.Response{Data: map[string]interface{}{"user": user}})
+	}))
+	t.Cleanup(userServer.Close)
+	config := NewConfig()
+	config.ListingService.URL, config.UserService.URL = listingServer.URL, userServer.URL
+	config.UserCache.Size = 0
+	return config
+}
+// queries returns the requests made to the user service so far, sorted.
+func (b *fakeBackends) queries() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queries := append([]string(nil), b.userQueries...)
+	sort.Strings(queries)
+	return queries
+}
+func newListingsWithOwners(userIDs ...int) []models.ListingResponse {
+	listings := make([]models.ListingResponse, len(userIDs))
+	for i, userID := range userIDs {
+		listings[i] = models.ListingResponse{ID: i + 1, UserID: userID, ListingType: "rent", Price: 1000 * (i + 1)}
+	}
+	return listings
+}
+func newService(config *Config) Service {
+	return NewService(NewServiceClient(config), NewUserCache(config.UserCache), config)
+}
+func TestServiceGetListingsPartialFailurePolicies(t *testing.T) {
+	backends := &fakeBackends{
+		listings: newListingsWithOwners(1, 2, 1),
+		users:    map[int]models.UserResponse{1: {ID: 1, Name: "Ana"}},
+	}
+	tests := []struct {
+		policy       string
+		wantErr      bool
+		wantListings []int
+		wantOwners   []string
+		wantWarnings []int
+	}{
+		{policy: PartialFailureSkip, wantListings: []int{1, 3}, wantOwners: []string{"Ana", "Ana"}, wantWarnings: []int{2}},
+		{policy: PartialFailureNull, wantListings: []int{1, 2, 3}, wantOwners: []string{"Ana", "", "Ana"}, wantWarnings: []int{2}},
+		{policy: PartialFailureFail, wantErr: true},
+	}
+	for _, tt := range tests {
+		for _, mode := range []string{UserLookupBatch, UserLookupFanOut} {
+			t.Run(tt.policy+"/"+mode, func(t *testing.T) {
+				config := backends.start(t)
+				config.PartialFailurePolicy, config.UserLookupMode = tt.policy, mode
+				service := newService(config)
+				response, err := service.GetListings(context.Background(), models.GetListingsRequest{})
+				if service.PartialFailures() != 1 {
+					t.Errorf("PartialFailures() = %d, want 1", service.PartialFailures())
+				}
+				if tt.wantErr {
+					if !errors.Is(err, ErrListingOwnerUnresolved) || statusCodeForError(err) != http.StatusBadGateway {
+						t.Fatalf("GetListings() = %v, want ErrListingOwnerUnresolved answered with a 502", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetListings() = %v", err)
+				}
+				var listings []int
+				var owners []string
+				for _, listing := range response.Listings {
+					listings = append(listings, listing.ID)
+					owner := ""
+					if listing.User != nil {
+						owner = listing.User.Name
+					}
+					owners = append(owners, owner)
+				}
+				var warnings []int
+				for _, warning := range response.Warnings {
+					warnings = append(warnings, warning.ListingID)
+				}
+				if !reflect.DeepEqual(listings, tt.wantListings) || !reflect.DeepEqual(owners, tt.wantOwners) {
+					t.Errorf("listings %v with owners %q, want %v with %q", listings, owners, tt.wantListings, tt.wantOwners)
+				}
+				if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+					t.Errorf("warnings for listings %v, want %v", warnings, tt.wantWarnings)
+				}
+			})
+		}
+	}
+}
+func TestServiceGetListingsFansOutOneRequestPerUser(t *testing.T) {
+	backends := &fakeBackends{
+		listings: newListingsWithOwners(3, 1, 2, 1, 3),
+		users:    map[int]models.UserResponse{1: {ID: 1, Name: "Ana"}, 2: {ID: 2, Name: "Bo"}, 3: {ID: 3, Name: "Cy"}},
+	}
+	config := backends.start(t)
+	config.UserLookupMode, config.UserLookupConcurrency = UserLookupFanOut, 2
+	response, err := newService(config).GetListings(context.Background(), models.GetListingsRequest{})
+	if err != nil {
+		t.Fatalf("GetListings() = %v", err)
+	}
+	if want := []string{"/users/1", "/users/2", "/users/3"}; !reflect.DeepEqual(backends.queries(), want) {
+		t.Errorf("user service requests = %v, want one per user %v", backends.queries(), want)
+	}
+	var got []string
+	for _, listing := range response.Listings {
+		got = append(got, fmt.Sprintf("%d:%s", listing.ID, listing.User.Name))
+	}
+	if want := []string{"1:Cy", "2:Ana", "3:Bo", "4:Ana", "5:Cy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listings = %v, want %v in the listing service's order", got, want)
+	}
+}
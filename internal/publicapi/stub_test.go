@@ -0,0 +1,80 @@
+package publicapi
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+var errConnectionRefused = errors.New("connection refused")
+// stubResult is what one call to stubHTTPClient returns: an error, or else a
+// response with the given status.
+type stubResult struct {
+	status int
+	err    error
+}
+// stubHTTPClient replays results in order, repeating the last one once they
+// run out, and records every call and every response body it hands out.
+type stubHTTPClient struct {
+	mu      sync.Mutex
+	results []stubResult
+	calls   int
+	bodies  []*stubBody
+}
+type stubBody struct {
+	io.Reader
+	closed bool
+}
+func (b *stubBody) Close() error {
+	b.closed = true
+	return nil
+}
+func newStubHTTPClient(results ...stubResult) *stubHTTPClient {
+	return &stubHTTPClient{results: results}
+}
+func (c *stubHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.next()
+}
+func (c *stubHTTPClient) PostForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.next()
+}
+func (c *stubHTTPClient) PatchForm(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return c.next()
+}
+func (c *stubHTTPClient) Delete(ctx context.Context, url string) (*http.Response, error) {
+	return c.next()
+}
+func (c *stubHTTPClient) next() (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index := c.calls
+	if index >= len(c.results) {
+		index = len(c.results) - 1
+	}
+	c.calls++
+	result := c.results[index]
+	if result.err != nil {
+		return nil, result.err
+	}
+	body := &stubBody{Reader: strings.NewReader("{}")}
+	c.bodies = append(c.bodies, body)
+	return &http.Response{StatusCode: result.status, Body: body}, nil
+}
+func (c *stubHTTPClient) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,152 @@
+package publicapi_test
+import (
+	"99-backend-exercise/internal/listing"
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/internal/publicapi"
+	"99-backend-exercise/internal/user"
+	"99-backend-exercise/pkg/database/databasetest"
+	"99-backend-exercise/pkg/tracing"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+// startUserService serves the user service's routes the way cmd/user-service
+// does, tracing included, and seeds it with one user.
+func startUserService(t *testing.T) (*httptest.Server, int) {
+	t.Helper()
+	conn := databasetest.NewSQLite(t, "user-service", user.Migrations())
+	userService := user.NewService(user.NewRepository(conn.DB))
+	created, err := userService.CreateUser(context.Background(), models.CreateUserRequest{Name: "Ana"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	handler := user.NewHandler(userService)
+	router := gin.New()
+	router.Use(otelgin.Middleware("user-service"))
+	router.GET("/users", handler.GetUsers)
+	router.GET("/users/:id", handler.GetUserByID)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, created.ID
+}
+// startListingService serves the listing service's routes the way
+// cmd/listing-service does, tracing included, and seeds it with one listing
+// owned by userID.
+func startListingService(t *testing.T, userID int) *httptest.Server {
+	t.Helper()
+	conn := databasetest.NewSQLite(t, "listing-service", listing.Migrations())
+	listingService := listing.NewService(listing.NewRepository(conn.DB), nil)
+	if _, err := listingService.CreateListing(context.Background(), models.CreateListingRequest{UserID: userID, ListingType: "rent", Price: 4500}); err != nil {
+		t.Fatalf("failed to create listing: %v", err)
+	}
+	handler := listing.NewHandler(listingService)
+	router := gin.New()
+	router.Use(otelgin.Middleware("listing-service"))
+	router.GET("/listings", handler.GetListings)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+// serverSpan returns the span otelgin recorded for route.
+func serverSpan(t *testing.T, spans tracetest.SpanStubs, route string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.SpanKind == trace.SpanKindServer && span.Name == route {
+			return span
+		}
+	}
+	t.Fatalf("no server span for %s among %d spans", route, len(spans))
+	return tracetest.SpanStub{}
+}
+// descendsFrom reports whether span is ancestor or one of its descendants.
+func descendsFrom(spans tracetest.SpanStubs, span, ancestor tracetest.SpanStub) bool {
+	byID := make(map[trace.SpanID]tracetest.SpanStub, len(spans))
+	for _, s := range spans {
+		byID[s.SpanContext.SpanID()] = s
+	}
+	for {
+		if span.SpanContext.SpanID() == ancestor.SpanContext.SpanID() {
+			return true
+		}
+		parent, ok := byID[span.Parent.SpanID()]
+		if !span.Parent.IsValid() || !ok {
+			return false
+		}
+		span = parent
+	}
+}
+func TestGetListingsPropagatesTraceContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, mode := range []string{publicapi.UserLookupBatch, publicapi.UserLookupFanOut} {
+		t.Run(mode, func(t *testing.T) {
+			provider, err := tracing.Setup(context.Background(), tracing.Config{ServiceName: "public-api", Exporter: tracing.ExporterMemory, SampleRatio: 1})
+			if err != nil {
+				t.Fatalf("tracing.Setup() = %v", err)
+			}
+			t.Cleanup(func() { provider.Shutdown(context.Background()) })
+			userServer, userID := startUserService(t)
+			listingServer := startListingService(t, userID)
+			config := publicapi.NewConfig()
+			config.UserService.URL, config.ListingService.URL = userServer.URL, listingServer.URL
+			config.UserLookupMode = mode
+			config.UserCache.Size = 0
+			handler := publicapi.NewHandler(publicapi.NewService(publicapi.NewServiceClient(config), publicapi.NewUserCache(config.UserCache), config))
+			router := gin.New()
+			router.Use(otelgin.Middleware("public-api"))
+			router.GET("/public-api/listings", handler.GetListings)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/public-api/listings", nil))
+			if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"name":"Ana"`) {
+				t.Fatalf("GET /public-api/listings = %d %s, want the listing with its owner", recorder.Code, recorder.Body)
+			}
+			spans := provider.Spans()
+			public := serverSpan(t, spans, "/public-api/listings")
+			userRoute := "/users/:id"
+			if mode == publicapi.UserLookupBatch {
+				userRoute = "/users"
+			}
+			backends := map[string]tracetest.SpanStub{
+				"user-service":    serverSpan(t, spans, userRoute),
+				"listing-service": serverSpan(t, spans, "/listings"),
+			}
+			for service, backend := range backends {
+				if backend.SpanContext.TraceID() != public.SpanContext.TraceID() {
+					t.Errorf("%s span is in trace %s, want the public API's trace %s", service, backend.SpanContext.TraceID(), public.SpanContext.TraceID())
+				}
+				if !backend.Parent.IsRemote() {
+					t.Errorf("%s span parent is not remote: the trace context was not taken from the request headers", service)
+				}
+				if !descendsFrom(spans, backend, public) {
+					t.Errorf("%s span does not descend from the public API request span", service)
+				}
+			}
+			queries := map[string]int{}
+			for _, span := range spans {
+				if !strings.HasPrefix(span.Name, "gorm.") {
+					continue
+				}
+				matched := false
+				for service, backend := range backends {
+					if span.Parent.SpanID() == backend.SpanContext.SpanID() {
+						queries[service]++
+						matched = true
+					}
+				}
+				if !matched {
+					t.Errorf("%s span has parent %s, want a backend request span", span.Name, span.Parent.SpanID())
+				}
+			}
+			for service := range backends {
+				if queries[service] == 0 {
+					t.Errorf("no database spans under the %s request span", service)
+				}
+			}
+		})
+	}
+}
@@ -2,11 +2,14 @@ package user
 import (
 	"99-backend-exercise/internal/models"
 	"99-backend-exercise/pkg/utils"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"github.com/gin-gonic/gin"
 )
+const maxBatchIDs = 100
 type Handler struct {
 	userService Service
 }
@@ -21,7 +24,24 @@ func (h *Handler) GetUsers(c *gin.Context) {
 		utils.RespondWithValidationError(c, err)
 		return
 	}
-	users, err := h.userService.GetUsers(request)
+	if request.IDs != "" {
+		h.getUsersByIDs(c, request.IDs)
+		return
+	}
+	users, err := h.userService.GetUsers(c.Request.Context(), request)
+	if err != nil {
+		utils.RespondWithError(c, statusCodeForError(err), "Failed to get users", err)
+		return
+	}
+	utils.RespondWithSuccess(c, users)
+}
+func (h *Handler) getUsersByIDs(c *gin.Context, idsParam string) {
+	ids, err := parseIDs(idsParam)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user IDs", err)
+		return
+	}
+	users, err := h.userService.GetUsersByIDs(c.Request.Context(), ids)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get users", err)
 		return
@@ -31,6 +51,21 @@ func (h *Handler) GetUsers(c *gin.Context) {
 	}
 	utils.RespondWithSuccess(c, response)
 }
+func parseIDs(idsParam string) ([]int, error) {
+	parts := strings.Split(idsParam, ",")
+	if len(parts) > maxBatchIDs {
+		return nil, fmt.Errorf("at most %d ids are allowed", maxBatchIDs)
+	}
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 func (h *Handler) GetUserByID(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
@@ -38,9 +73,9 @@ func (h *Handler) GetUserByID(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID", err)
 		return
 	}
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		utils.RespondWithError(c, http.StatusNotFound, "User not found", err)
+		utils.RespondWithErrorCode(c, statusCodeForError(err), errorCodeForError(err), "Failed to get user", err)
 		return
 	}
 	response := map[string]interface{}{
@@ -51,11 +86,10 @@ func (h *Handler) GetUserByID(c *gin.Context) {
 func (h *Handler) CreateUser(c *gin.Context) {
 	var request models.CreateUserRequest
 	if err := c.ShouldBind(&request); err != nil {
-		fmt.Println(err)
 		utils.RespondWithValidationError(c, err)
 		return
 	}
-	user, err := h.userService.CreateUser(request)
+	user, err := h.userService.CreateUser(c.Request.Context(), request)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create user", err)
 		return
@@ -65,3 +99,55 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	}
 	utils.RespondWithSuccess(c, response)
 }
+func (h *Handler) UpdateUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	var request models.UpdateUserRequest
+	if err := c.ShouldBind(&request); err != nil {
+		utils.RespondWithValidationError(c, err)
+		return
+	}
+	user, err := h.userService.UpdateUser(c.Request.Context(), id, request)
+	if err != nil {
+		utils.RespondWithErrorCode(c, statusCodeForError(err), errorCodeForError(err), "Failed to update user", err)
+		return
+	}
+	response := map[string]interface{}{
+		"user": user,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func (h *Handler) DeleteUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	user, err := h.userService.DeleteUser(c.Request.Context(), id)
+	if err != nil {
+		utils.RespondWithErrorCode(c, statusCodeForError(err), errorCodeForError(err), "Failed to delete user", err)
+		return
+	}
+	response := map[string]interface{}{
+		"user": user,
+	}
+	utils.RespondWithSuccess(c, response)
+}
+func statusCodeForError(err error) int {
+	if errors.Is(err, ErrUserNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+func errorCodeForError(err error) string {
+	if errors.Is(err, ErrUserNotFound) {
+		return models.ErrorCodeUserNotFound
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package user
+import (
+	"99-backend-exercise/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"github.com/gin-gonic/gin"
+)
+func TestParseIDs(t *testing.T) {
+	ids := make([]string, maxBatchIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+	tests := []struct {
+		name    string
+		param   string
+		want    []int
+		wantErr bool
+	}{
+		{name: "single", param: "7", want: []int{7}},
+		{name: "several in request order", param: "3,1,2", want: []int{3, 1, 2}},
+		{name: "spaces around IDs", param: " 3 , 1", want: []int{3, 1}},
+		{name: "duplicates", param: "2,1,2,2", want: []int{2, 1, 2, 2}},
+		{name: "maximum number of IDs", param: strings.Join(ids[:maxBatchIDs], ","), want: func() []int {
+			want := make([]int, maxBatchIDs)
+			for i := range want {
+				want[i] = i + 1
+			}
+			return want
+		}()},
+		{name: "too many IDs", param: strings.Join(ids, ","), wantErr: true},
+		{name: "empty", param: "", wantErr: true},
+		{name: "empty element", param: "1,,2", wantErr: true},
+		{name: "trailing comma", param: "1,2,", wantErr: true},
+		{name: "non-numeric", param: "1,abc", wantErr: true},
+		{name: "decimal", param: "1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIDs(tt.param)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseIDs(%q) = %v, want an error", tt.param, got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseIDs(%q) = %v, %v, want %v", tt.param, got, err, tt.want)
+			}
+		})
+	}
+}
+func TestHandlerGetUsersRejectsListingCursors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newTestRepository(t)
+	user := createTestUser(t, repo, "Ana")
+	router := gin.New()
+	router.GET("/users", NewHandler(NewService(repo)).GetUsers)
+	tests := []struct {
+		name       string
+		cursor     models.Cursor
+		wantStatus int
+	}{
+		{name: "user cursor", cursor: models.Cursor{Value: user.CreatedAt.UnixNano(), ID: user.ID}, wantStatus: http.StatusOK},
+		{name: "listing cursor", cursor: models.Cursor{Sort: "price_asc", Value: 1000, ID: user.ID}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users?cursor="+models.EncodeCursor(tt.cursor), nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("GET /users = %d %s, want %d", recorder.Code, recorder.Body, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_idempotency_keys_created_at;
+DROP TABLE IF EXISTS user_idempotency_keys;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_idempotency_keys (
+	idempotency_key TEXT PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT,
+	body BLOB,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_idempotency_keys_created_at ON user_idempotency_keys (created_at);
@@ -1,13 +1,18 @@
 package user
 import (
 	"99-backend-exercise/internal/models"
+	"context"
+	"time"
 	"gorm.io/gorm"
 )
 type Repository interface {
-	GetAll(offset, limit int) ([]models.User, error)
-	GetByID(id int) (*models.User, error)
-	Create(user *models.User) error
-	Count() (int64, error)
+	GetAll(ctx context.Context, offset, limit int, cursor *models.Cursor) ([]models.User, error)
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []int) ([]models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, user *models.User) error
+	Count(ctx context.Context) (int64, error)
 }
 type repository struct {
 	db *gorm.DB
@@ -15,24 +20,45 @@ type repository struct {
 func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
-func (r *repository) GetAll(offset, limit int) ([]models.User, error) {
+func (r *repository) GetAll(ctx context.Context, offset, limit int, cursor *models.Cursor) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&users).Error
+	query := r.db.WithContext(ctx).Order("created_at DESC, id DESC").Limit(limit)
+	if cursor != nil {
+		createdAt := time.Unix(0, cursor.Value).UTC()
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, cursor.ID)
+	} else {
+		query = query.Offset(offset)
+	}
+	err := query.Find(&users).Error
 	return users, err
 }
-func (r *repository) GetByID(id int) (*models.User, error) {
+func (r *repository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).Unscoped().First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
-func (r *repository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+func (r *repository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+func (r *repository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+func (r *repository) Delete(ctx context.Context, user *models.User) error {
+	if err := r.db.WithContext(ctx).Delete(user).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Unscoped().First(user, user.ID).Error
 }
-func (r *repository) Count() (int64, error) {
+func (r *repository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
 	return count, err
 }
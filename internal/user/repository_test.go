@@ -0,0 +1,59 @@
+package user
+import (
+	"99-backend-exercise/internal/models"
+	"99-backend-exercise/pkg/database/databasetest"
+	"context"
+	"sort"
+	"testing"
+)
+func newTestRepository(t *testing.T) *repository {
+	return &repository{db: databasetest.NewSQLite(t, "user-service", Migrations()).DB}
+}
+func createTestUser(t *testing.T, repo *repository, name string) models.User {
+	t.Helper()
+	user := models.User{Name: name}
+	if err := repo.Create(context.Background(), &user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+func TestRepositoryGetByIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	ana, bo, cy := createTestUser(t, repo, "Ana"), createTestUser(t, repo, "Bo"), createTestUser(t, repo, "Cy")
+	if err := repo.Delete(ctx, &bo); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ids  []int
+		want []int
+	}{
+		{name: "all found", ids: []int{ana.ID, cy.ID}, want: []int{ana.ID, cy.ID}},
+		{name: "missing IDs are omitted", ids: []int{ana.ID, 999, cy.ID, 1000}, want: []int{ana.ID, cy.ID}},
+		{name: "none found", ids: []int{999}, want: nil},
+		{name: "duplicates are returned once", ids: []int{cy.ID, cy.ID}, want: []int{cy.ID}},
+		{name: "deleted users are included", ids: []int{bo.ID}, want: []int{bo.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.GetByIDs(ctx, tt.ids)
+			if err != nil {
+				t.Fatalf("GetByIDs(%v) = %v", tt.ids, err)
+			}
+			var got []int
+			for _, user := range users {
+				got = append(got, user.ID)
+			}
+			sort.Ints(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetByIDs(%v) returned IDs %v, want %v", tt.ids, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("GetByIDs(%v) returned IDs %v, want %v", tt.ids, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,19 @@
 package user
 import (
 	"99-backend-exercise/internal/models"
+	"context"
 	"errors"
+	"gorm.io/gorm"
 )
 type Service interface {
-	GetUsers(request models.GetUsersRequest) ([]models.UserResponse, error)
-	GetUserByID(id int) (*models.UserResponse, error)
-	CreateUser(request models.CreateUserRequest) (*models.UserResponse, error)
+	GetUsers(ctx context.Context, request models.GetUsersRequest) (*models.UserListResponse, error)
+	GetUserByID(ctx context.Context, id int) (*models.UserResponse, error)
+	GetUsersByIDs(ctx context.Context, ids []int) ([]models.UserResponse, error)
+	CreateUser(ctx context.Context, request models.CreateUserRequest) (*models.UserResponse, error)
+	UpdateUser(ctx context.Context, id int, request models.UpdateUserRequest) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, id int) (*models.UserResponse, error)
 }
+var ErrUserNotFound = errors.New("user not found")
 type service struct {
 	userRepo Repository
 }
@@ -16,35 +22,126 @@ func NewService(userRepo Repository) Service {
 		userRepo: userRepo,
 	}
 }
-func (s *service) GetUsers(request models.GetUsersRequest) ([]models.UserResponse, error) {
+func (s *service) GetUsers(ctx context.Context, request models.GetUsersRequest) (*models.UserListResponse, error) {
+	if request.IsCursorMode() {
+		return s.getUsersAfterCursor(ctx, request)
+	}
 	offset := request.GetOffset()
 	limit := request.GetPageSize()
-	users, err := s.userRepo.GetAll(offset, limit)
+	users, err := s.userRepo.GetAll(ctx, offset, limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.userRepo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserListResponse{
+		Users:              toUserResponses(users),
+		PaginationResponse: models.NewPaginationResponse(request.PaginationRequest, total),
+	}, nil
+}
+func (s *service) getUsersAfterCursor(ctx context.Context, request models.GetUsersRequest) (*models.UserListResponse, error) {
+	cursor, err := models.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	// Users are only ever paged by creation time, so a cursor issued for
+	// another sort (e.g. a listings cursor) cannot be continued here.
+	if cursor != nil && cursor.Sort != "" {
+		return nil, models.ErrInvalidCursor
+	}
+	limit := request.GetLimit()
+	users, err := s.userRepo.GetAll(ctx, 0, limit+1, cursor)
 	if err != nil {
 		return nil, err
 	}
+	total, err := s.userRepo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nextCursor := ""
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		nextCursor = models.EncodeCursor(models.Cursor{Value: last.CreatedAt.UnixNano(), ID: last.ID})
+	}
+	return &models.UserListResponse{
+		Users:              toUserResponses(users),
+		PaginationResponse: models.NewCursorPaginationResponse(request.PaginationRequest, total, nextCursor),
+	}, nil
+}
+func toUserResponses(users []models.User) []models.UserResponse {
 	responses := make([]models.UserResponse, len(users))
 	for i, user := range users {
 		responses[i] = user.ToResponse()
 	}
-	return responses, nil
+	return responses
 }
-func (s *service) GetUserByID(id int) (*models.UserResponse, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *service) GetUserByID(ctx context.Context, id int) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, err
 	}
 	response := user.ToResponse()
 	return &response, nil
 }
-func (s *service) CreateUser(request models.CreateUserRequest) (*models.UserResponse, error) {
+func (s *service) GetUsersByIDs(ctx context.Context, ids []int) ([]models.UserResponse, error) {
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return toUserResponses(users), nil
+}
+func (s *service) CreateUser(ctx context.Context, request models.CreateUserRequest) (*models.UserResponse, error) {
 	user := &models.User{
 		Name: request.Name,
 	}
-	err := s.userRepo.Create(user)
+	err := s.userRepo.Create(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 	response := user.ToResponse()
 	return &response, nil
 }
+func (s *service) UpdateUser(ctx context.Context, id int, request models.UpdateUserRequest) (*models.UserResponse, error) {
+	user, err := s.getActiveUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Name != nil {
+		user.Name = *request.Name
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	response := user.ToResponse()
+	return &response, nil
+}
+func (s *service) DeleteUser(ctx context.Context, id int) (*models.UserResponse, error) {
+	user, err := s.getActiveUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Delete(ctx, user); err != nil {
+		return nil, err
+	}
+	response := user.ToResponse()
+	return &response, nil
+}
+func (s *service) getActiveUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt.Valid {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
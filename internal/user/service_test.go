@@ -0,0 +1,98 @@
+package user
+import (
+	"99-backend-exercise/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+func TestServiceDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	kept, deleted := createTestUser(t, repo, "Ana"), createTestUser(t, repo, "Bo")
+	svc := NewService(repo)
+	response, err := svc.DeleteUser(ctx, deleted.ID)
+	if err != nil || response.DeletedAt == nil {
+		t.Fatalf("DeleteUser() = %+v, %v, want the user with deleted_at set", response, err)
+	}
+	if _, err := svc.DeleteUser(ctx, deleted.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("second DeleteUser() = %v, want ErrUserNotFound", err)
+	}
+	name := "Cy"
+	if _, err := svc.UpdateUser(ctx, deleted.ID, models.UpdateUserRequest{Name: &name}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdateUser(deleted) = %v, want ErrUserNotFound", err)
+	}
+	// Deleted users still resolve by ID so that their listings keep an owner.
+	if user, err := svc.GetUserByID(ctx, deleted.ID); err != nil || user.DeletedAt == nil || user.Name != "Bo" {
+		t.Errorf("GetUserByID(deleted) = %+v, %v, want the deleted user", user, err)
+	}
+	list, err := svc.GetUsers(ctx, models.GetUsersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Users) != 1 || list.Users[0].ID != kept.ID || list.Total != 1 {
+		t.Errorf("GetUsers() = %+v, want only user %d", list, kept.ID)
+	}
+}
+func TestServiceMissingUser(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(newTestRepository(t))
+	name := "Cy"
+	if _, err := svc.GetUserByID(ctx, 42); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetUserByID() = %v, want ErrUserNotFound", err)
+	}
+	if _, err := svc.UpdateUser(ctx, 42, models.UpdateUserRequest{Name: &name}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdateUser() = %v, want ErrUserNotFound", err)
+	}
+	if _, err := svc.DeleteUser(ctx, 42); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("DeleteUser() = %v, want ErrUserNotFound", err)
+	}
+}
+func TestServiceGetUsersRejectsCursorsForOtherSorts(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	createTestUser(t, repo, "Ana")
+	user := createTestUser(t, repo, "Bo")
+	svc := NewService(repo)
+	valid := models.EncodeCursor(models.Cursor{Value: user.CreatedAt.UnixNano(), ID: user.ID})
+	if _, err := svc.GetUsers(ctx, models.GetUsersRequest{PaginationRequest: models.PaginationRequest{Cursor: valid}}); err != nil {
+		t.Fatalf("GetUsers() with a user cursor = %v", err)
+	}
+	for _, sort := range []string{models.SortByCreatedAt + "_desc", models.SortByPrice + "_asc"} {
+		cursor := models.EncodeCursor(models.Cursor{Sort: sort, Value: user.CreatedAt.UnixNano(), ID: user.ID})
+		_, err := svc.GetUsers(ctx, models.GetUsersRequest{PaginationRequest: models.PaginationRequest{Cursor: cursor}})
+		if !errors.Is(err, models.ErrInvalidCursor) {
+			t.Errorf("GetUsers() with a cursor for sort %q = %v, want ErrInvalidCursor", sort, err)
+		}
+	}
+}
+func TestServiceGetUsersOutsideUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*60*60)
+	t.Cleanup(func() { time.Local = local })
+	repo := newTestRepository(t)
+	var want []int
+	for _, name := range []string{"Ana", "Bo", "Cy", "Di", "Ed"} {
+		want = append([]int{createTestUser(t, repo, name).ID}, want...)
+	}
+	svc := NewService(repo)
+	request := models.GetUsersRequest{PaginationRequest: models.PaginationRequest{Limit: 2}}
+	var got []int
+	for page := 0; page < 5; page++ {
+		response, err := svc.GetUsers(context.Background(), request)
+		if err != nil {
+			t.Fatalf("GetUsers(cursor %q) = %v", request.Cursor, err)
+		}
+		for _, user := range response.Users {
+			got = append(got, user.ID)
+		}
+		if !response.HasNext {
+			break
+		}
+		request.Cursor = response.NextCursor
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("paged through %v, want %v", got, want)
+	}
+}
@@ -1,49 +1,195 @@
 package database
 
 import (
-	"database/sql"
+	"99-backend-exercise/pkg/logging"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	_ "modernc.org/sqlite"
 )
 
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
 type Connection struct {
 	DB *gorm.DB
 }
 type DatabaseConfig struct {
-	DBPath string
+	Driver          string
+	DBPath          string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MigrateOnStart  bool
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	JournalMode     string
+	BusyTimeout     time.Duration
+	ForeignKeys     bool
+	SlowQuery       time.Duration
+}
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+}
+type Health struct {
+	Status  string            `json:"status"`
+	Driver  string            `json:"driver"`
+	Error   string            `json:"error,omitempty"`
+	Pool    PoolStats         `json:"pool"`
+	Pragmas map[string]string `json:"pragmas,omitempty"`
 }
 
 func NewDatabaseConfig() *DatabaseConfig {
 	return &DatabaseConfig{
-		DBPath: getEnv("DB_PATH", "./database.db"),
+		Driver:          getEnv("DB_DRIVER", DriverSQLite),
+		DBPath:          getEnv("DB_PATH", "./database.db"),
+		Host:            getEnv("DB_HOST", "localhost"),
+		Port:            getEnv("DB_PORT", "5432"),
+		User:            getEnv("DB_USER", "postgres"),
+		Password:        getEnv("DB_PASSWORD", ""),
+		Name:            getEnv("DB_NAME", "backend_exercise"),
+		SSLMode:         getEnv("DB_SSLMODE", "disable"),
+		MigrateOnStart:  getEnvBool("DB_MIGRATE_ON_START", true),
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 10),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		JournalMode:     getEnv("DB_SQLITE_JOURNAL_MODE", "WAL"),
+		BusyTimeout:     getEnvDuration("DB_SQLITE_BUSY_TIMEOUT", 5*time.Second),
+		ForeignKeys:     getEnvBool("DB_SQLITE_FOREIGN_KEYS", true),
+		SlowQuery:       getEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+	}
+}
+func (c *DatabaseConfig) DSN() string {
+	if c.Driver == DriverPostgres {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     c.Host + ":" + c.Port,
+			Path:     c.Name,
+			RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.BusyTimeout.Milliseconds()))
+	if c.JournalMode != "" {
+		pragmas.Add("_pragma", fmt.Sprintf("journal_mode(%s)", c.JournalMode))
+	}
+	foreignKeys := 0
+	if c.ForeignKeys {
+		foreignKeys = 1
+	}
+	pragmas.Add("_pragma", fmt.Sprintf("foreign_keys(%d)", foreignKeys))
+	separator := "?"
+	if strings.Contains(c.DBPath, "?") {
+		separator = "&"
 	}
+	return c.DBPath + separator + pragmas.Encode()
+}
+func (c *DatabaseConfig) String() string {
+	if c.Driver == DriverPostgres {
+		return fmt.Sprintf("%s:%s/%s", c.Host, c.Port, c.Name)
+	}
+	return c.DBPath
 }
 func Connect(config *DatabaseConfig) (*Connection, error) {
-	sqlDB, err := sql.Open("sqlite", config.DBPath)
+	dialector, err := newDialector(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+		return nil, err
 	}
-	db, err := gorm.Open(sqlite.Dialector{Conn: sqlDB}, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	// Timestamps are written in UTC. SQLite compares them as text, so rows and
+	// query arguments must agree on the zone for range and keyset queries.
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:  newGormLogger(logging.Logger("gorm"), config.SlowQuery),
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
-		sqlDB.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	if err := registerMetricsCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register metrics callbacks: %w", err)
+	}
+	if err := registerTracingCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register tracing callbacks: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
-	log.Printf("Successfully connected to SQLite database at: %s", config.DBPath)
+	logging.Logger("database").Info("Connected to database", "driver", config.Driver, "address", config.String())
 	return &Connection{DB: db}, nil
 }
-func (c *Connection) AutoMigrate(models ...interface{}) error {
-	return c.DB.AutoMigrate(models...)
+func newDialector(config *DatabaseConfig) (gorm.Dialector, error) {
+	switch config.Driver {
+	case DriverSQLite:
+		return sqlite.Dialector{DriverName: "sqlite", DSN: config.DSN()}, nil
+	case DriverPostgres:
+		return postgres.Open(config.DSN()), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
+}
+func (c *Connection) Health() Health {
+	health := Health{
+		Status: "ok",
+		Driver: c.DB.Dialector.Name(),
+	}
+	sqlDB, err := c.DB.DB()
+	if err != nil {
+		health.Status, health.Error = "down", err.Error()
+		return health
+	}
+	stats := sqlDB.Stats()
+	health.Pool = PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+	}
+	if err := sqlDB.Ping(); err != nil {
+		health.Status, health.Error = "down", err.Error()
+		return health
+	}
+	if health.Driver == DriverSQLite {
+		health.Pragmas = make(map[string]string)
+		for _, pragma := range []string{"journal_mode", "busy_timeout", "foreign_keys"} {
+			var value string
+			if err := sqlDB.QueryRow("PRAGMA " + pragma).Scan(&value); err == nil {
+				health.Pragmas[pragma] = value
+			}
+		}
+	}
+	return health
 }
 func (c *Connection) Close() error {
 	sqlDB, err := c.DB.DB()
@@ -58,3 +204,24 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,40 @@
+// Package databasetest provides database fixtures for tests.
+package databasetest
+
+import (
+	"99-backend-exercise/pkg/database"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// NewSQLite opens an in-memory SQLite database private to the test and, unless
+// fsys is nil, applies the service's migrations from it. Tests that need more
+// than one database tell them apart by service. The pool is limited to a single
+// connection that never expires, so every query sees the same database and the
+// database lives until the test's cleanup closes it.
+func NewSQLite(t testing.TB, service string, fsys fs.FS) *database.Connection {
+	t.Helper()
+	config := database.NewDatabaseConfig()
+	config.Driver = database.DriverSQLite
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name() + "_" + service)
+	config.DBPath = "file:" + name + "?mode=memory&cache=shared"
+	config.MaxOpenConns, config.MaxIdleConns = 1, 1
+	config.ConnMaxLifetime, config.ConnMaxIdleTime = 0, 0
+	conn, err := database.Connect(config)
+	if err != nil {
+		t.Fatalf("failed to open %s test database: %v", service, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if fsys == nil {
+		return conn
+	}
+	migrator, err := database.NewMigrator(conn, service, fsys)
+	if err != nil {
+		t.Fatalf("failed to load %s migrations: %v", service, err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate %s test database: %v", service, err)
+	}
+	return conn
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogger sends GORM's output to slog. Statements are logged at debug
+// level, slow ones at warn and failed ones at error; which of those appear is
+// decided by the slog logger's level rather than GORM's LogMode.
+type gormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+}
+
+func newGormLogger(l *slog.Logger, slowThreshold time.Duration) logger.Interface {
+	return &gormLogger{
+		logger:        l,
+		slowThreshold: slowThreshold,
+	}
+}
+func (l *gormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+}
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+}
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+}
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	sql, rows := fc()
+	args := []any{
+		"sql", sql,
+		"rows", rows,
+		"duration_ms", float64(elapsed.Microseconds()) / 1000,
+	}
+	if level == slog.LevelError {
+		args = append(args, "error", err)
+	}
+	l.logger.Log(ctx, level, msg, args...)
+}
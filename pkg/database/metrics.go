@@ -0,0 +1,55 @@
+package database
+
+import (
+	"99-backend-exercise/pkg/metrics"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const metricsStartKey = "metrics:start"
+
+// registerMetricsCallbacks times every statement GORM runs and reports it to
+// pkg/metrics, labelled by operation and table.
+func registerMetricsCallbacks(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("metrics:before_create", startTimer),
+		callback.Create().After("gorm:create").Register("metrics:after_create", observeQuery("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", startTimer),
+		callback.Query().After("gorm:query").Register("metrics:after_query", observeQuery("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", startTimer),
+		callback.Update().After("gorm:update").Register("metrics:after_update", observeQuery("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", startTimer),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", observeQuery("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", startTimer),
+		callback.Row().After("gorm:row").Register("metrics:after_row", observeQuery("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", startTimer),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", observeQuery("raw")),
+	)
+}
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		outcome := "success"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			outcome = "error"
+		}
+		metrics.ObserveDBQuery(operation, table, outcome, time.Since(start))
+	}
+}
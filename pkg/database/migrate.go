@@ -0,0 +1,313 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const schemaMigrationsTable = "schema_migrations"
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+type Migrator struct {
+	db         *gorm.DB
+	service    string
+	migrations []Migration
+}
+type appliedMigration struct {
+	Version   int
+	AppliedAt time.Time
+}
+
+// NewMigrator loads the migrations for the connection's driver from fsys, which
+// is expected to hold one directory per driver (e.g. sqlite/0001_init.up.sql).
+// Applied versions are tracked per service so several services can share a database.
+func NewMigrator(conn *Connection, service string, fsys fs.FS) (*Migrator, error) {
+	driver := conn.DB.Dialector.Name()
+	migrations, err := loadMigrations(fsys, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         conn.DB,
+		service:    service,
+		migrations: migrations,
+	}, nil
+}
+func loadMigrations(fsys fs.FS, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s migrations: %w", driver, err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(matches[1])
+		content, err := fs.ReadFile(fsys, path.Join(driver, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS ` + schemaMigrationsTable + ` (
+		service VARCHAR(64) NOT NULL,
+		version INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (service, version)
+	)`).Error
+}
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, fmt.Errorf("failed to create %s table: %w", schemaMigrationsTable, err)
+	}
+	var rows []appliedMigration
+	err := m.db.Table(schemaMigrationsTable).
+		Select("version, applied_at").
+		Where("service = ?", m.service).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Exec(
+				"INSERT INTO "+schemaMigrationsTable+" (service, version, name, applied_at) VALUES (?, ?, ?, ?)",
+				m.service, migration.Version, migration.Name, time.Now().UTC(),
+			).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s has no down file", migration.Version, migration.Name)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Exec(
+				"DELETE FROM "+schemaMigrationsTable+" WHERE service = ? AND version = ?",
+				m.service, migration.Version,
+			).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to roll back migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+func execStatements(tx *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a migration script into statements at the semicolons
+// that end them. Semicolons inside quoted strings and identifiers, comments and
+// PostgreSQL dollar-quoted bodies ($$ ... $$) do not end a statement, and chunks
+// holding nothing but whitespace and comments are dropped. Statements that
+// contain their own top-level semicolons, such as SQLite trigger bodies
+// (BEGIN ... END), are not supported.
+func splitStatements(script string) []string {
+	var statements []string
+	start, hasCode := 0, false
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"':
+			hasCode = true
+			i = skipQuoted(script, i, c)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == '$':
+			hasCode = true
+			if tag := dollarQuoteTag.FindString(script[i:]); tag != "" {
+				if end := strings.Index(script[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, hasCode = i+1, false
+		case !unicode.IsSpace(rune(c)):
+			hasCode = true
+		}
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
+
+var dollarQuoteTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// skipQuoted returns the index of the quote that closes the string or identifier
+// opened at script[start]. A doubled quote is an escaped quote.
+func skipQuoted(script string, start int, quote byte) int {
+	for i := start + 1; i < len(script); i++ {
+		if script[i] != quote {
+			continue
+		}
+		if i+1 < len(script) && script[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return len(script)
+}
+
+// RunMigrateCommand implements the "migrate up|down [steps]|status" subcommand.
+func RunMigrateCommand(migrator *Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up()
+		fmt.Printf("Applied %d migration(s) for %s\n", count, migrator.service)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		count, err := migrator.Down(steps)
+		fmt.Printf("Rolled back %d migration(s) for %s\n", count, migrator.service)
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", "-"
+			if status.AppliedAt != nil {
+				state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+}
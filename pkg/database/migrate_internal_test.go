@@ -0,0 +1,72 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "one statement per semicolon",
+			script: "CREATE TABLE a (id INTEGER);\nCREATE TABLE b (id INTEGER);\n",
+			want:   []string{"CREATE TABLE a (id INTEGER)", "CREATE TABLE b (id INTEGER)"},
+		},
+		{
+			name:   "no trailing semicolon",
+			script: "DROP TABLE a;\nDROP TABLE b",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{
+			name:   "empty statements",
+			script: " ;\n;DROP TABLE a;;\n",
+			want:   []string{"DROP TABLE a"},
+		},
+		{
+			name:   "semicolon in a string",
+			script: "INSERT INTO a (note) VALUES ('x; y');\nDROP TABLE b;",
+			want:   []string{"INSERT INTO a (note) VALUES ('x; y')", "DROP TABLE b"},
+		},
+		{
+			name:   "escaped quote in a string",
+			script: "INSERT INTO a (note) VALUES ('it''s; fine');",
+			want:   []string{"INSERT INTO a (note) VALUES ('it''s; fine')"},
+		},
+		{
+			name:   "semicolon in a quoted identifier",
+			script: `CREATE TABLE "a;b" (id INTEGER);`,
+			want:   []string{`CREATE TABLE "a;b" (id INTEGER)`},
+		},
+		{
+			name:   "line comments",
+			script: "-- drop everything; really\nDROP TABLE a; -- done;\n-- trailing comment",
+			want:   []string{"-- drop everything; really\nDROP TABLE a"},
+		},
+		{
+			name:   "block comments",
+			script: "/* step 1; */ DROP TABLE a;\n/* only a comment; */;",
+			want:   []string{"/* step 1; */ DROP TABLE a"},
+		},
+		{
+			name:   "dollar-quoted body",
+			script: "CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN NEW.a := 1; RETURN NEW; END; $body$ LANGUAGE plpgsql;\nDROP TABLE b;",
+			want:   []string{"CREATE FUNCTION f() RETURNS trigger AS $body$ BEGIN NEW.a := 1; RETURN NEW; END; $body$ LANGUAGE plpgsql", "DROP TABLE b"},
+		},
+		{
+			name:   "anonymous dollar quotes and positional parameters",
+			script: "DO $$ BEGIN PERFORM 1; END $$;\nSELECT $1;",
+			want:   []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT $1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package database_test
+
+import (
+	"99-backend-exercise/pkg/database"
+	"99-backend-exercise/pkg/database/databasetest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestMigrator(t *testing.T, conn *database.Connection, service string, fsys fstest.MapFS) *database.Migrator {
+	t.Helper()
+	migrator, err := database.NewMigrator(conn, service, fsys)
+	if err != nil {
+		t.Fatalf("NewMigrator() = %v", err)
+	}
+	return migrator
+}
+
+func migrationFile(sql string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(sql)}
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"sqlite/0001_create_widgets.up.sql":     migrationFile("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT);"),
+		"sqlite/0001_create_widgets.down.sql":   migrationFile("DROP TABLE widgets;"),
+		"sqlite/0002_add_widget_color.up.sql":   migrationFile("ALTER TABLE widgets ADD COLUMN color TEXT;\nCREATE INDEX idx_widgets_color ON widgets (color);"),
+		"sqlite/0002_add_widget_color.down.sql": migrationFile("DROP INDEX idx_widgets_color;\nALTER TABLE widgets DROP COLUMN color;"),
+		"sqlite/0010_create_gadgets.up.sql":     migrationFile("CREATE TABLE gadgets (id INTEGER PRIMARY KEY);"),
+		"sqlite/0010_create_gadgets.down.sql":   migrationFile("DROP TABLE gadgets;"),
+	}
+}
+
+func appliedVersions(t *testing.T, migrator *database.Migrator) []int {
+	t.Helper()
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	versions := []int{}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func hasTable(conn *database.Connection, table string) bool {
+	return conn.DB.Migrator().HasTable(table)
+}
+
+func TestNewMigratorOrdersMigrationsByVersion(t *testing.T) {
+	fsys := testMigrations()
+	fsys["sqlite/README.md"] = migrationFile("not a migration")
+	fsys["sqlite/0003_skipped.sql"] = migrationFile("SELECT 1;")
+	fsys["postgres/0005_postgres_only.up.sql"] = migrationFile("SELECT 1;")
+	statuses, err := newTestMigrator(t, databasetest.NewSQLite(t, "widget-service", nil), "widget-service", fsys).Status()
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	var got []string
+	for _, status := range statuses {
+		got = append(got, status.Name)
+	}
+	want := []string{"create_widgets", "add_widget_color", "create_gadgets"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("migrations = %v, want %v in version order", got, want)
+	}
+	if statuses[1].Version != 2 || statuses[1].Down == "" {
+		t.Errorf("migration 2 = %+v, want version 2 with its down script", statuses[1].Migration)
+	}
+}
+
+func TestNewMigratorRejectsInvalidMigrationSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "missing up file",
+			fsys: fstest.MapFS{"sqlite/0001_init.down.sql": migrationFile("DROP TABLE t;")},
+			want: "has no up file",
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"sqlite/0001_init.up.sql":  migrationFile("CREATE TABLE t (id INTEGER);"),
+				"sqlite/0001_other.up.sql": migrationFile("CREATE TABLE u (id INTEGER);"),
+			},
+			want: "conflicting names",
+		},
+		{
+			name: "no directory for the driver",
+			fsys: fstest.MapFS{"postgres/0001_init.up.sql": migrationFile("CREATE TABLE t (id INTEGER);")},
+			want: "failed to read sqlite migrations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := database.NewMigrator(databasetest.NewSQLite(t, "widget-service", nil), "widget-service", tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewMigrator() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigratorUpIsIdempotent(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	migrator := newTestMigrator(t, conn, "widget-service", testMigrations())
+	if count, err := migrator.Up(); count != 3 || err != nil {
+		t.Fatalf("first Up() = %d, %v, want 3, nil", count, err)
+	}
+	for i := 0; i < 2; i++ {
+		if count, err := migrator.Up(); count != 0 || err != nil {
+			t.Fatalf("repeated Up() = %d, %v, want 0, nil", count, err)
+		}
+	}
+	if got := appliedVersions(t, migrator); !reflect.DeepEqual(got, []int{1, 2, 10}) {
+		t.Errorf("applied versions = %v, want [1 2 10]", got)
+	}
+	if !hasTable(conn, "widgets") || !hasTable(conn, "gadgets") {
+		t.Error("Up() did not create the widgets and gadgets tables")
+	}
+}
+
+func TestMigratorUpAppliesOnlyPendingMigrations(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	fsys := testMigrations()
+	initial := fstest.MapFS{"sqlite/0001_create_widgets.up.sql": fsys["sqlite/0001_create_widgets.up.sql"]}
+	if count, err := newTestMigrator(t, conn, "widget-service", initial).Up(); count != 1 || err != nil {
+		t.Fatalf("Up() with one migration = %d, %v, want 1, nil", count, err)
+	}
+	migrator := newTestMigrator(t, conn, "widget-service", fsys)
+	if count, err := migrator.Up(); count != 2 || err != nil {
+		t.Fatalf("Up() after adding migrations = %d, %v, want 2, nil", count, err)
+	}
+	if got := appliedVersions(t, migrator); !reflect.DeepEqual(got, []int{1, 2, 10}) {
+		t.Errorf("applied versions = %v, want [1 2 10]", got)
+	}
+}
+
+func TestMigratorUpStopsAtFailingMigration(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	fsys := testMigrations()
+	// The first statement succeeds; the failing second one must roll it back.
+	fsys["sqlite/0002_add_widget_color.up.sql"] = migrationFile("ALTER TABLE widgets ADD COLUMN color TEXT;\nCREATE INDEX idx_widgets_color ON missing (color);")
+	migrator := newTestMigrator(t, conn, "widget-service", fsys)
+	count, err := migrator.Up()
+	if count != 1 || err == nil || !strings.Contains(err.Error(), "0002_add_widget_color") {
+		t.Fatalf("Up() = %d, %v, want 1 and an error naming 0002_add_widget_color", count, err)
+	}
+	if got := appliedVersions(t, migrator); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("applied versions = %v, want [1]", got)
+	}
+	if conn.DB.Migrator().HasColumn("widgets", "color") {
+		t.Error("the failed migration's first statement was not rolled back")
+	}
+	if hasTable(conn, "gadgets") {
+		t.Error("a migration after the failed one was applied")
+	}
+}
+
+func TestMigratorDownRollsBackInReverseOrder(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	migrator := newTestMigrator(t, conn, "widget-service", testMigrations())
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up() = %v", err)
+	}
+	if count, err := migrator.Down(1); count != 1 || err != nil {
+		t.Fatalf("Down(1) = %d, %v, want 1, nil", count, err)
+	}
+	if got := appliedVersions(t, migrator); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("applied versions after Down(1) = %v, want [1 2]", got)
+	}
+	if hasTable(conn, "gadgets") {
+		t.Error("Down(1) did not drop the gadgets table")
+	}
+	if count, err := migrator.Down(10); count != 2 || err != nil {
+		t.Fatalf("Down(10) = %d, %v, want 2, nil", count, err)
+	}
+	if got := appliedVersions(t, migrator); len(got) != 0 {
+		t.Errorf("applied versions after Down(10) = %v, want none", got)
+	}
+	if hasTable(conn, "widgets") {
+		t.Error("Down(10) did not drop the widgets table")
+	}
+	if count, err := migrator.Down(1); count != 0 || err != nil {
+		t.Fatalf("Down(1) with nothing applied = %d, %v, want 0, nil", count, err)
+	}
+	if count, err := migrator.Up(); count != 3 || err != nil {
+		t.Fatalf("Up() after rolling back = %d, %v, want 3, nil", count, err)
+	}
+}
+
+func TestMigratorDownRequiresDownFile(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	fsys := testMigrations()
+	delete(fsys, "sqlite/0002_add_widget_color.down.sql")
+	migrator := newTestMigrator(t, conn, "widget-service", fsys)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up() = %v", err)
+	}
+	count, err := migrator.Down(3)
+	if count != 1 || err == nil || !strings.Contains(err.Error(), "has no down file") {
+		t.Fatalf("Down(3) = %d, %v, want 1 and a missing down file error", count, err)
+	}
+	if got := appliedVersions(t, migrator); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("applied versions = %v, want [1 2]", got)
+	}
+}
+
+func TestMigratorTracksServicesSeparately(t *testing.T) {
+	conn := databasetest.NewSQLite(t, "widget-service", nil)
+	widgets := newTestMigrator(t, conn, "widget-service", testMigrations())
+	if _, err := widgets.Up(); err != nil {
+		t.Fatalf("Up() = %v", err)
+	}
+	other := newTestMigrator(t, conn, "other-service", fstest.MapFS{
+		"sqlite/0001_create_others.up.sql": migrationFile("CREATE TABLE others (id INTEGER PRIMARY KEY);"),
+	})
+	if count, err := other.Up(); count != 1 || err != nil {
+		t.Fatalf("Up() for a second service = %d, %v, want 1, nil", count, err)
+	}
+	if got := appliedVersions(t, widgets); !reflect.DeepEqual(got, []int{1, 2, 10}) {
+		t.Errorf("widget-service applied versions = %v, want [1 2 10]", got)
+	}
+}
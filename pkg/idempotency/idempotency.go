@@ -0,0 +1,208 @@
+// Package idempotency makes writes safe to retry. A write that carries an
+// Idempotency-Key header is applied at most once; repeating it returns the
+// response of the first attempt instead of running the handler again.
+package idempotency
+
+import (
+	"99-backend-exercise/pkg/logging"
+	"99-backend-exercise/pkg/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier attempt.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxKeyLength bounds the keys accepted from clients.
+const maxKeyLength = 255
+
+// Config sets how long a key is remembered. Once it expires, the key may be
+// used for a new write.
+type Config struct {
+	TTL time.Duration
+}
+
+// NewConfig reads IDEMPOTENCY_KEY_TTL.
+func NewConfig() Config {
+	return Config{TTL: getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)}
+}
+
+// record is one keyed write. Status is zero while the write is in progress.
+type record struct {
+	IdempotencyKey string `gorm:"primaryKey"`
+	Fingerprint    string
+	Status         int
+	ContentType    string
+	Body           []byte
+	CreatedAt      time.Time
+}
+
+// Store keeps the keyed writes of one service in table, which the service's
+// migrations create.
+type Store struct {
+	db     *gorm.DB
+	table  string
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func NewStore(db *gorm.DB, table string, config Config) *Store {
+	return &Store{
+		db:     db,
+		table:  table,
+		ttl:    config.TTL,
+		logger: logging.Logger("idempotency"),
+	}
+}
+
+// Middleware runs each keyed write once. A repeat of a completed write gets its
+// stored response, a repeat of a write still in progress gets a 409, and
+// reusing a key for a different request gets a 422. Writes that fail with a
+// 5xx or never finish release their key, so that they can be retried. Requests
+// without a key, and GETs, pass through untouched.
+func (s *Store) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid idempotency key", fmt.Errorf("%s must be at most %d characters", Header, maxKeyLength))
+			c.Abort()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Failed to read request body", err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request, body)
+		existing, err := s.claim(c.Request.Context(), key, fingerprint)
+		switch {
+		case err != nil:
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to check idempotency key", err)
+			c.Abort()
+			return
+		case existing == nil:
+		case existing.Fingerprint != fingerprint:
+			utils.RespondWithError(c, http.StatusUnprocessableEntity, "Idempotency key reused", fmt.Errorf("%s was already used for a different request", Header))
+			c.Abort()
+			return
+		case existing.Status == 0:
+			utils.RespondWithError(c, http.StatusConflict, "Request in progress", fmt.Errorf("a request with this %s is still in progress", Header))
+			c.Abort()
+			return
+		default:
+			c.Header(ReplayedHeader, "true")
+			c.Data(existing.Status, existing.ContentType, existing.Body)
+			c.Abort()
+			return
+		}
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		finished := false
+		defer func() {
+			// The request may have been canceled; the key must be settled anyway.
+			ctx := context.WithoutCancel(c.Request.Context())
+			var err error
+			if finished && writer.Status() < http.StatusInternalServerError {
+				err = s.complete(ctx, key, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+			} else {
+				err = s.release(ctx, key)
+			}
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to settle idempotency key", "error", err)
+			}
+		}()
+		c.Next()
+		finished = true
+	}
+}
+
+// claim records key as in progress and returns nil, or returns the record that
+// already holds the key. Expired records are purged first.
+func (s *Store) claim(ctx context.Context, key, fingerprint string) (*record, error) {
+	now := s.db.NowFunc()
+	if err := s.db.WithContext(ctx).Table(s.table).Where("created_at < ?", now.Add(-s.ttl)).Delete(&record{}).Error; err != nil {
+		return nil, err
+	}
+	claimed := record{IdempotencyKey: key, Fingerprint: fingerprint, CreatedAt: now}
+	result := s.db.WithContext(ctx).Table(s.table).Clauses(clause.OnConflict{DoNothing: true}).Create(&claimed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+	var existing record
+	err := s.db.WithContext(ctx).Table(s.table).Where("idempotency_key = ?", key).Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released between the insert and the read: answer as if it were
+		// still in progress, so that the client tries again.
+		return &record{Fingerprint: fingerprint}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+func (s *Store) complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	return s.db.WithContext(ctx).Table(s.table).Where("idempotency_key = ?", key).Updates(map[string]interface{}{
+		"status":       status,
+		"content_type": contentType,
+		"body":         body,
+	}).Error
+}
+func (s *Store) release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Table(s.table).Where("idempotency_key = ?", key).Delete(&record{}).Error
+}
+
+// requestFingerprint identifies what a key was used for, so that the key cannot
+// replay the response of one request to a different one.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body for replays.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,186 @@
+package idempotency_test
+
+import (
+	"99-backend-exercise/internal/user"
+	"99-backend-exercise/pkg/database/databasetest"
+	"99-backend-exercise/pkg/idempotency"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves POST /users/:id and GET /users/:id through the
+// middleware of a store backed by the user service's schema. The handler
+// answers with the status returned by respond, or panics when respond does.
+func newTestRouter(t *testing.T, config idempotency.Config, respond func(n int64) int) (*gin.Engine, *atomic.Int64) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	conn := databasetest.NewSQLite(t, "user-service", user.Migrations())
+	var calls atomic.Int64
+	handler := func(c *gin.Context) {
+		n := calls.Add(1)
+		c.JSON(respond(n), gin.H{"call": n})
+	}
+	router := gin.New()
+	router.Use(gin.Recovery(), idempotency.NewStore(conn.DB, "user_idempotency_keys", config).Middleware())
+	router.POST("/users/:id", handler)
+	router.GET("/users/:id", handler)
+	return router, &calls
+}
+
+func send(router http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(idempotency.Header, key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func ok(int64) int { return http.StatusCreated }
+
+func TestMiddlewareReplaysCompletedWrites(t *testing.T) {
+	router, calls := newTestRouter(t, idempotency.NewConfig(), ok)
+	first := send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+	second := send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want once", calls.Load())
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("replay = %d %q (%s), want %d %q (%s)", second.Code, second.Body, second.Header().Get("Content-Type"), first.Code, first.Body, first.Header().Get("Content-Type"))
+	}
+	if first.Header().Get(idempotency.ReplayedHeader) != "" || second.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("%s = %q then %q, want it only on the replay", idempotency.ReplayedHeader, first.Header().Get(idempotency.ReplayedHeader), second.Header().Get(idempotency.ReplayedHeader))
+	}
+	if other := send(router, http.MethodPost, "/users/1", "key-2", "name=Ana"); other.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("write with another key = %d after %d calls, want it applied", other.Code, calls.Load())
+	}
+}
+
+func TestMiddlewareRejectsKeyReuse(t *testing.T) {
+	router, calls := newTestRouter(t, idempotency.NewConfig(), ok)
+	send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+	for _, tt := range []struct{ path, body string }{
+		{path: "/users/1", body: "name=Bo"},
+		{path: "/users/2", body: "name=Ana"},
+		{path: "/users/1?force=true", body: "name=Ana"},
+	} {
+		if recorder := send(router, http.MethodPost, tt.path, "key-1", tt.body); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("POST %s %q with a used key = %d %s, want 422", tt.path, tt.body, recorder.Code, recorder.Body)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", calls.Load())
+	}
+}
+
+func TestMiddlewareRejectsRepeatsInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	router, calls := newTestRouter(t, idempotency.NewConfig(), func(int64) int {
+		close(started)
+		<-release
+		return http.StatusCreated
+	})
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send(router, http.MethodPost, "/users/1", "key-1", "name=Ana") }()
+	<-started
+	repeat := send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("first attempt = %d %s, want 201", first.Code, first.Body)
+	}
+	if repeat.Code != http.StatusConflict {
+		t.Errorf("repeat while in progress = %d %s, want 409", repeat.Code, repeat.Body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", calls.Load())
+	}
+}
+
+func TestMiddlewareReleasesFailedWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(n int64) int
+	}{
+		{name: "server error", respond: func(n int64) int {
+			if n == 1 {
+				return http.StatusServiceUnavailable
+			}
+			return http.StatusCreated
+		}},
+		{name: "panic", respond: func(n int64) int {
+			if n == 1 {
+				panic("write failed")
+			}
+			return http.StatusCreated
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, calls := newTestRouter(t, idempotency.NewConfig(), tt.respond)
+			if first := send(router, http.MethodPost, "/users/1", "key-1", "name=Ana"); first.Code < http.StatusInternalServerError {
+				t.Fatalf("first attempt = %d, want a 5xx", first.Code)
+			}
+			retry := send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+			if retry.Code != http.StatusCreated || retry.Header().Get(idempotency.ReplayedHeader) != "" || calls.Load() != 2 {
+				t.Errorf("retry = %d after %d calls, want the write applied again", retry.Code, calls.Load())
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsClientErrors(t *testing.T) {
+	router, calls := newTestRouter(t, idempotency.NewConfig(), func(int64) int { return http.StatusBadRequest })
+	send(router, http.MethodPost, "/users/1", "key-1", "name=")
+	if retry := send(router, http.MethodPost, "/users/1", "key-1", "name="); retry.Code != http.StatusBadRequest || retry.Header().Get(idempotency.ReplayedHeader) != "true" || calls.Load() != 1 {
+		t.Errorf("repeat of a rejected write = %d after %d calls, want the 400 replayed", retry.Code, calls.Load())
+	}
+}
+
+func TestMiddlewareForgetsExpiredKeys(t *testing.T) {
+	router, calls := newTestRouter(t, idempotency.Config{TTL: time.Millisecond}, ok)
+	send(router, http.MethodPost, "/users/1", "key-1", "name=Ana")
+	time.Sleep(5 * time.Millisecond)
+	if retry := send(router, http.MethodPost, "/users/1", "key-1", "name=Bo"); retry.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("write with an expired key = %d after %d calls, want it applied", retry.Code, calls.Load())
+	}
+}
+
+func TestMiddlewarePassesThrough(t *testing.T) {
+	tests := []struct {
+		name, method, key string
+	}{
+		{name: "write without key", method: http.MethodPost},
+		{name: "read with key", method: http.MethodGet, key: "key-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, calls := newTestRouter(t, idempotency.NewConfig(), ok)
+			for i := 0; i < 2; i++ {
+				if recorder := send(router, tt.method, "/users/1", tt.key, ""); recorder.Header().Get(idempotency.ReplayedHeader) != "" {
+					t.Fatalf("%s was replayed", tt.method)
+				}
+			}
+			if calls.Load() != 2 {
+				t.Errorf("handler ran %d times, want twice", calls.Load())
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejectsOverlongKeys(t *testing.T) {
+	router, calls := newTestRouter(t, idempotency.NewConfig(), ok)
+	key := strings.Repeat("k", 256)
+	if recorder := send(router, http.MethodPost, "/users/1", key, "name=Ana"); recorder.Code != http.StatusBadRequest || calls.Load() != 0 {
+		t.Errorf("POST with a %d character key = %d after %d calls, want 400", len(key), recorder.Code, calls.Load())
+	}
+	if recorder := send(router, http.MethodPost, "/users/1", key[:255], "name=Ana"); recorder.Code != http.StatusCreated {
+		t.Errorf("POST with a 255 character key = %d, want 201", recorder.Code)
+	}
+}
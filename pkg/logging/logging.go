@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config sets the minimum level logged by every component, with overrides for
+// individual components (e.g. "http", "gorm", "publicapi").
+type Config struct {
+	Service string
+	Level   slog.Level
+	Levels  map[string]slog.Level
+}
+
+var (
+	config           = Config{Level: slog.LevelInfo}
+	output io.Writer = os.Stdout
+)
+
+// NewConfig reads LOG_LEVEL and LOG_LEVELS, a comma separated list of
+// component=level pairs such as "gorm=debug,http=warn". Levels that cannot be
+// parsed are ignored.
+func NewConfig(service string) Config {
+	config := Config{
+		Service: service,
+		Level:   parseLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo),
+		Levels:  make(map[string]slog.Level),
+	}
+	for _, pair := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		component, level, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		var parsed slog.Level
+		if err := parsed.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			continue
+		}
+		config.Levels[strings.TrimSpace(component)] = parsed
+	}
+	return config
+}
+func (c Config) LevelFor(component string) slog.Level {
+	if level, ok := c.Levels[component]; ok {
+		return level
+	}
+	return c.Level
+}
+
+// Setup applies config to the loggers created from then on and routes the
+// standard library logger and gin's debug output through slog, so stray log
+// calls end up as JSON too.
+func Setup(c Config) {
+	config = c
+	slog.SetDefault(Logger("default"))
+	ginLogger := Logger("gin")
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		ginLogger.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, nuHandlers int) {
+		ginLogger.Debug("route", "method", httpMethod, "path", absolutePath, "handler", handlerName, "handlers", nuHandlers)
+	}
+}
+
+// Logger returns a JSON logger for component. Records logged with a context
+// carry the request ID and trace ID found in it.
+func Logger(component string) *slog.Logger {
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{Level: config.LevelFor(component)})
+	return slog.New(contextHandler{handler}).With("service", config.Service, "component", component)
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+func parseLevel(value string, defaultLevel slog.Level) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return defaultLevel
+	}
+	return level
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var generatedRequestID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// captureOutput sends the records of loggers created during the test to a
+// buffer.
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := output
+	output = &buf
+	t.Cleanup(func() { output = previous })
+	return &buf
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "incoming ID", incoming: "client-request-1", keep: true},
+		{name: "missing ID"},
+		{name: "overlong ID", incoming: strings.Repeat("x", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureOutput(t)
+			var handled string
+			router := gin.New()
+			router.Use(RequestIDMiddleware(), AccessLogMiddleware(Logger("http")))
+			router.GET("/ping", func(c *gin.Context) {
+				handled = RequestID(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+			request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			if tt.incoming != "" {
+				request.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			echoed := recorder.Header().Get(RequestIDHeader)
+			if tt.keep && echoed != tt.incoming {
+				t.Fatalf("response %s = %q, want the incoming %q", RequestIDHeader, echoed, tt.incoming)
+			}
+			if !tt.keep && !generatedRequestID.MatchString(echoed) {
+				t.Fatalf("response %s = %q, want a generated ID", RequestIDHeader, echoed)
+			}
+			if handled != echoed {
+				t.Errorf("request context ID = %q, want %q", handled, echoed)
+			}
+			var record struct {
+				Msg       string `json:"msg"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("access log %q is not a single JSON record: %v", buf, err)
+			}
+			if record.Msg != "request" || record.RequestID != echoed {
+				t.Errorf("access log = %s, want a request record with request_id %q", buf, echoed)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddlewareGeneratesDistinctIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		id := recorder.Header().Get(RequestIDHeader)
+		if seen[id] {
+			t.Fatalf("request ID %q was generated twice", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by route and status.",
+	}, []string{"service", "method", "route", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests handled, by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method", "route", "status"})
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Calls made to upstream services, by outcome.",
+	}, []string{"upstream", "operation", "outcome"})
+	upstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Latency of calls made to upstream services, by outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "operation", "outcome"})
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Latency of database queries, by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table", "outcome"})
+)
+
+// Middleware records a request count and latency for every request served by
+// service. Requests that match no route are reported under "unmatched" so that
+// arbitrary paths cannot blow up the label cardinality.
+func Middleware(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(service, c.Request.Method, route, status).Inc()
+		httpRequestDuration.WithLabelValues(service, c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics of the default registry in the Prometheus text
+// format.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+func ObserveUpstreamCall(upstream, operation, outcome string, duration time.Duration) {
+	upstreamRequests.WithLabelValues(upstream, operation, outcome).Inc()
+	upstreamRequestDuration.WithLabelValues(upstream, operation, outcome).Observe(duration.Seconds())
+}
+func ObserveDBQuery(operation, table, outcome string, duration time.Duration) {
+	dbQueryDuration.WithLabelValues(operation, table, outcome).Observe(duration.Seconds())
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+	ExporterNone   = "none"
+)
+
+// Config selects where spans go. The OTLP exporter reads its endpoint and
+// headers from the standard OTEL_EXPORTER_OTLP_* variables.
+type Config struct {
+	ServiceName string
+	Exporter    string
+	SampleRatio float64
+}
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	memory   *tracetest.InMemoryExporter
+}
+
+func NewConfig(serviceName string) Config {
+	return Config{
+		ServiceName: serviceName,
+		Exporter:    getEnv("OTEL_TRACES_EXPORTER", ExporterNone),
+		SampleRatio: getEnvFloat("OTEL_TRACES_SAMPLE_RATIO", 1),
+	}
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. With the "none" exporter spans are still created and propagated,
+// but never exported.
+func Setup(ctx context.Context, config Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	}
+	provider := &Provider{}
+	switch config.Exporter {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithSyncer(exporter))
+	case ExporterMemory:
+		provider.memory = tracetest.NewInMemoryExporter()
+		options = append(options, sdktrace.WithSyncer(provider.memory))
+	case ExporterNone, "":
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", config.Exporter)
+	}
+	provider.provider = sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider.provider)
+	return provider, nil
+}
+
+// Spans returns the spans recorded so far by the in-memory exporter, or nil
+// for any other exporter.
+func (p *Provider) Spans() tracetest.SpanStubs {
+	if p.memory == nil {
+		return nil
+	}
+	return p.memory.GetSpans()
+}
+
+// Shutdown flushes pending spans.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.provider.Shutdown(ctx)
+}
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
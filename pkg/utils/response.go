@@ -6,32 +6,40 @@ import (
 )
 func RespondWithSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, models.Response{
-		Result: true,
-		Data:   data,
+		Result:  true,
+		Version: models.ResponseVersion,
+		Data:    data,
 	})
 }
 func RespondWithSuccessAndMessage(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusOK, models.Response{
 		Result:  true,
+		Version: models.ResponseVersion,
 		Message: message,
 		Data:    data,
 	})
 }
 func RespondWithError(c *gin.Context, statusCode int, message string, err error) {
+	RespondWithErrorCode(c, statusCode, "", message, err)
+}
+func RespondWithErrorCode(c *gin.Context, statusCode int, errorCode, message string, err error) {
 	var errorDetail interface{}
 	if err != nil {
 		errorDetail = err.Error()
 	}
 	c.JSON(statusCode, models.Response{
-		Result:  false,
-		Message: message,
-		Error:   errorDetail,
-		Code:    statusCode,
+		Result:    false,
+		Version:   models.ResponseVersion,
+		Message:   message,
+		Error:     errorDetail,
+		ErrorCode: errorCode,
+		Code:      statusCode,
 	})
 }
 func RespondWithValidationError(c *gin.Context, err error) {
 	c.JSON(http.StatusBadRequest, models.Response{
 		Result:  false,
+		Version: models.ResponseVersion,
 		Message: "Validation failed",
 		Error:   err.Error(),
 		Code:    http.StatusBadRequest,
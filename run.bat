@@ -25,8 +25,6 @@ echo Creating bin directory...
 if not exist bin mkdir bin
 echo Installing dependencies...
 call :install-deps
-echo Installing Python dependencies...
-pip install -r python-libs.txt
 echo Setting CGO_ENABLED=0 for pure Go build...
 set CGO_ENABLED=0
 echo Building user service...
@@ -41,7 +39,12 @@ if errorlevel 1 (
     echo Error building public API
     goto end
 )
-echo Python listing service ready to run...
+echo Building listing service...
+go build -o bin/listing-service.exe ./cmd/listing-service
+if errorlevel 1 (
+    echo Error building listing service
+    goto end
+)
 echo All services built successfully!
 goto end
 
@@ -52,8 +55,8 @@ echo Starting all services...
 echo Starting user service on port 8001...
 start /min "User Service" bin/user-service.exe
 timeout /t 2 /nobreak >nul
-echo Starting listing service (Python) on port 6000...
-start /min "Listing Service" python listing_service.py
+echo Starting listing service on port 6000...
+start /min "Listing Service" bin/listing-service.exe
 timeout /t 2 /nobreak >nul
 echo Starting public API on port 8000...
 start /min "Public API" bin/public-api.exe